@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/resultcache"
+)
+
+func addBumpSearchCacheCmd(root *cobra.Command) {
+	bumpCmd := &cobra.Command{
+		Use:   "bump-search-cache",
+		Short: "Invalidate cached search results after the collection is re-indexed",
+		Run: func(cmd *cobra.Command, args []string) {
+			bumpSearchCache(cmd)
+		},
+	}
+
+	root.AddCommand(bumpCmd)
+	bumpCmd.Flags().StringP("config", "c", "", "Config file path")
+	bumpCmd.Flags().BoolP("dev", "d", false, "Run with development config")
+}
+
+func bumpSearchCache(cmd *cobra.Command) {
+	config := loadConfig(cmd)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: config.Redis.Addr,
+	})
+	defer rdb.Close()
+
+	cache := resultcache.NewRedisCache(rdb, config.Milvus.CollectionName, 0, config.SearchCache.QuantizationStep)
+	version, err := cache.BumpVersion(context.Background())
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+	logrus.Infof("search cache of %s bumped to version %d", config.Milvus.CollectionName, version)
+}
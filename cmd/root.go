@@ -10,5 +10,7 @@ func New() *cobra.Command {
 		Run:   nil,
 	}
 	addServeCmd(root)
+	addBumpSearchCacheCmd(root)
+	addSetRoleCmd(root)
 	return root
 }
@@ -39,8 +39,9 @@ func serve(cmd *cobra.Command) {
 	ctx := context.Background()
 
 	var terminableJobs []job.WithGracefulShutdown
-	terminableJobs = append(terminableJobs, server.RunServer(ctx, config))
-	terminableJobs = append(terminableJobs, server.RunHttpServer(ctx, config))
+	grpcServer, keys := server.RunServer(ctx, config)
+	terminableJobs = append(terminableJobs, grpcServer)
+	terminableJobs = append(terminableJobs, server.RunHttpServer(ctx, config, keys))
 	terminableJobs = append(terminableJobs, jobs.StartJobs(config)...)
 
 	terminateOnSignals(ctx, terminableJobs)
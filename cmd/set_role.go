@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rbac"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+func addSetRoleCmd(root *cobra.Command) {
+	setRoleCmd := &cobra.Command{
+		Use:   "set-role",
+		Short: "Set the role of a user, e.g. to make the first admin",
+		Run: func(cmd *cobra.Command, args []string) {
+			setRole(cmd)
+		},
+	}
+
+	root.AddCommand(setRoleCmd)
+	setRoleCmd.Flags().StringP("config", "c", "", "Config file path")
+	setRoleCmd.Flags().BoolP("dev", "d", false, "Run with development config")
+	setRoleCmd.Flags().String("email", "", "Email of the user")
+	setRoleCmd.Flags().String("phone", "", "Phone number of the user, if they have no email")
+	setRoleCmd.Flags().String("role", rbac.RoleAdmin, "Role to set: user, support or admin")
+}
+
+func setRole(cmd *cobra.Command) {
+	config := loadConfig(cmd)
+	email, _ := cmd.Flags().GetString("email")
+	phone, _ := cmd.Flags().GetString("phone")
+	role, _ := cmd.Flags().GetString("role")
+	if !rbac.IsRole(role) {
+		logrus.Fatalf("unknown role %q", role)
+	}
+
+	store := storage.NewStorage(&config.MainDB)
+	var user *storage.UserAccount
+	var err error
+	if email != "" {
+		user, err = store.GetUserByEmail(email)
+	} else {
+		user, err = store.GetUserByPhoneNumber(phone)
+	}
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+	if err := store.SetUserRole(user.ID, role); err != nil {
+		logrus.Fatal(err.Error())
+	}
+	logrus.Infof("user %d is now %s", user.ID, role)
+}
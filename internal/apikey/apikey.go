@@ -0,0 +1,225 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// keyPrefix starts every key, so leaked keys are easy to spot, e.g. by secret scanners.
+const keyPrefix = "dvk_"
+
+// displayPrefixLength is how much of a key is stored in clear to tell keys apart.
+const displayPrefixLength = 12
+
+const (
+	// keyCacheSize and keyCacheTTL bound the keys Authenticate keeps in memory. A revoked key keeps working on other
+	// instances for up to keyCacheTTL. Unknown keys are cached apart, in up to missCacheSize entries, so trying
+	// random keys can't push out the real ones.
+	keyCacheSize  = 1024
+	missCacheSize = 256
+	keyCacheTTL   = 30 * time.Second
+	// usageKey is the redis hash counting calls until FlushUsage moves them to postgres, usageFlushingKey holds the
+	// counts being moved.
+	usageKey         = "apikey:usage"
+	usageFlushingKey = "apikey:usage:flushing"
+	usageLockKey     = "apikey:usage:lock"
+	usageLockExpire  = time.Minute
+)
+
+var (
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrRateLimited = errors.New("api key rate limit exceeded")
+)
+
+// cachedKey is a key looked up by Authenticate.
+type cachedKey struct {
+	key       *storage.ApiKey
+	expiresAt time.Time
+}
+
+// Manager issues api keys and authenticates and meters requests made with them.
+type Manager struct {
+	storage *storage.Storage
+	client  *redis.Client
+	cache   *lru.Cache[string, cachedKey]
+	// misses holds when the hashes of unknown keys expire.
+	misses *lru.Cache[string, time.Time]
+}
+
+func NewManager(store *storage.Storage, client *redis.Client) *Manager {
+	cache, _ := lru.New[string, cachedKey](keyCacheSize)
+	misses, _ := lru.New[string, time.Time](missCacheSize)
+	return &Manager{
+		storage: store,
+		client:  client,
+		cache:   cache,
+		misses:  misses,
+	}
+}
+
+// Create issues a new key and returns it in clear, which is not possible later.
+func (m *Manager) Create(key *storage.ApiKey) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	plain := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	key.Prefix = plain[:displayPrefixLength]
+	key.KeyHash = hashKey(plain)
+	if err := m.storage.CreateApiKey(key); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// Authenticate returns the active key matching plain, or ErrInvalidKey. Keys, and the hashes of unknown keys, are
+// cached for keyCacheTTL. Other errors mean the key couldn't be looked up and aren't cached.
+func (m *Manager) Authenticate(plain string) (*storage.ApiKey, error) {
+	hash := hashKey(plain)
+	now := time.Now()
+	cached, ok := m.cache.Get(hash)
+	if !ok || now.After(cached.expiresAt) {
+		if expiresAt, ok := m.misses.Get(hash); ok && now.Before(expiresAt) {
+			return nil, ErrInvalidKey
+		}
+		key, err := m.storage.GetApiKeyByHash(hash)
+		if err == storage.ErrApiKeyNotFound {
+			m.cache.Remove(hash)
+			m.misses.Add(hash, now.Add(keyCacheTTL))
+			return nil, ErrInvalidKey
+		}
+		if err != nil {
+			return nil, err
+		}
+		cached = cachedKey{key: key, expiresAt: now.Add(keyCacheTTL)}
+		m.cache.Add(hash, cached)
+	}
+	if !cached.key.IsActive() {
+		return nil, ErrInvalidKey
+	}
+	return cached.key, nil
+}
+
+// Forget drops a key from the cache of Authenticate, e.g. once it is revoked.
+func (m *Manager) Forget(key *storage.ApiKey) {
+	m.cache.Remove(key.KeyHash)
+}
+
+func rateKey(id uint, minute int64) string {
+	return fmt.Sprintf("apikey:rate:%d:%d", id, minute)
+}
+
+// Allow counts a request made with the key in the current minute and reports ErrRateLimited once the key used up
+// its limit.
+func (m *Manager) Allow(ctx context.Context, key *storage.ApiKey) error {
+	if key.RateLimit <= 0 {
+		return nil
+	}
+	redisKey := rateKey(key.ID, time.Now().Unix()/60)
+	count, err := m.client.Incr(ctx, redisKey).Result()
+	if err != nil {
+		// don't fail partner requests because redis is down
+		logrus.Error("failed to count api key request: ", err)
+		return nil
+	}
+	if count == 1 {
+		m.client.Expire(ctx, redisKey, time.Minute)
+	}
+	if count > int64(key.RateLimit) {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// RecordUsage counts a call of an rpc with the key for billing in redis, see FlushUsage.
+func (m *Manager) RecordUsage(ctx context.Context, key *storage.ApiKey, method string) {
+	field := fmt.Sprintf("%d|%s|%s", key.ID, time.Now().UTC().Format("2006-01-02"), method)
+	if err := m.client.HIncrBy(ctx, usageKey, field, 1).Err(); err != nil {
+		logrus.Error("failed to record api key usage: ", err)
+	}
+}
+
+// FlushUsage moves the usage counted by RecordUsage to postgres. Only one instance flushes at a time, and counts
+// that could not be stored are kept for the next flush.
+func (m *Manager) FlushUsage(ctx context.Context) error {
+	locked, err := m.client.SetNX(ctx, usageLockKey, 1, usageLockExpire).Result()
+	if err != nil || !locked {
+		return err
+	}
+	defer m.client.Del(ctx, usageLockKey)
+	exists, err := m.client.Exists(ctx, usageFlushingKey).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		// nothing else renames the usage hash while the lock is held
+		pending, err := m.client.Exists(ctx, usageKey).Result()
+		if err != nil || pending == 0 {
+			return err
+		}
+		if err := m.client.Rename(ctx, usageKey, usageFlushingKey).Err(); err != nil {
+			return err
+		}
+	}
+	fields, err := m.client.HGetAll(ctx, usageFlushingKey).Result()
+	if err != nil {
+		return err
+	}
+	usage := make([]storage.ApiKeyUsage, 0, len(fields))
+	for field, count := range fields {
+		u, err := parseUsage(field, count)
+		if err != nil {
+			logrus.Errorf("dropping invalid api key usage %q: %v", field, err)
+			continue
+		}
+		usage = append(usage, u)
+	}
+	if err := m.storage.AddApiKeyUsage(usage, time.Now()); err != nil {
+		return err
+	}
+	return m.client.Del(ctx, usageFlushingKey).Err()
+}
+
+// parseUsage parses a field and count of the usage hash written by RecordUsage.
+func parseUsage(field string, count string) (storage.ApiKeyUsage, error) {
+	parts := strings.SplitN(field, "|", 3)
+	if len(parts) != 3 {
+		return storage.ApiKeyUsage{}, errors.New("malformed field")
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return storage.ApiKeyUsage{}, err
+	}
+	day, err := time.Parse("2006-01-02", parts[1])
+	if err != nil {
+		return storage.ApiKeyUsage{}, err
+	}
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return storage.ApiKeyUsage{}, err
+	}
+	return storage.ApiKeyUsage{
+		ApiKeyID: uint(id),
+		Day:      day,
+		Method:   parts[2],
+		Count:    n,
+	}, nil
+}
+
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
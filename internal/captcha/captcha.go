@@ -0,0 +1,91 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	BackendNone = "none"
+	BackendHttp = "http"
+)
+
+// Verifier checks captcha tokens solved by users.
+type Verifier interface {
+	// Enabled reports whether captchas are configured at all. Without them, logins never require one.
+	Enabled() bool
+	Verify(ctx context.Context, token string, ip string) (bool, error)
+}
+
+// Config selects the Verifier. The http backend works with the siteverify api of reCAPTCHA, hCaptcha and
+// Turnstile, e.g. Url https://hcaptcha.com/siteverify.
+type Config struct {
+	Backend string
+	Url     string
+	Secret  string
+	Timeout int64
+}
+
+func NewVerifier(config Config) (Verifier, error) {
+	switch config.Backend {
+	case BackendNone, "":
+		return NoopVerifier{}, nil
+	case BackendHttp:
+		return NewHttpVerifier(config), nil
+	}
+	return nil, fmt.Errorf("unknown captcha backend %q", config.Backend)
+}
+
+// NoopVerifier is used when captchas are not configured.
+type NoopVerifier struct{}
+
+func (NoopVerifier) Enabled() bool {
+	return false
+}
+
+func (NoopVerifier) Verify(context.Context, string, string) (bool, error) {
+	return true, nil
+}
+
+// HttpVerifier verifies tokens with a siteverify api.
+type HttpVerifier struct {
+	url    string
+	secret string
+	client *resty.Client
+}
+
+func NewHttpVerifier(config Config) HttpVerifier {
+	return HttpVerifier{
+		url:    config.Url,
+		secret: config.Secret,
+		client: resty.New().SetTimeout(time.Duration(config.Timeout) * time.Second),
+	}
+}
+
+func (HttpVerifier) Enabled() bool {
+	return true
+}
+
+func (v HttpVerifier) Verify(ctx context.Context, token string, ip string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	var result struct {
+		Success bool `json:"success"`
+	}
+	resp, err := v.client.R().SetContext(ctx).SetResult(&result).SetFormData(map[string]string{
+		"secret":   v.secret,
+		"response": token,
+		"remoteip": ip,
+	}).Post(v.url)
+	if err != nil {
+		return false, err
+	}
+	if resp.IsError() {
+		return false, fmt.Errorf("captcha verification responded with %s", resp.Status())
+	}
+	return result.Success, nil
+}
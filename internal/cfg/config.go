@@ -4,7 +4,19 @@ import (
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/bootstrap"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/captcha"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/cfg_utils"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/loginguard"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/notify"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/oidc"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/otp"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/productmeta"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/sms"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/twofactor"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/verification"
 )
 
 type Config struct {
@@ -17,11 +29,7 @@ type Config struct {
 
 	MainDB storage.DBConfig `mapstructure:"main_db" yaml:"main_db"`
 
-	JWT struct {
-		Secret             string
-		AuthTokenExpire    int64 `mapstructure:"auth_token_expire" yaml:"auth_token_expire"`
-		RefreshTokenExpire int64 `mapstructure:"refresh_token_expire" yaml:"refresh_token_expire"`
-	}
+	JWT token.Config
 
 	HttpServer struct {
 		Port int
@@ -47,6 +55,51 @@ type Config struct {
 		Addr string
 	}
 
+	SearchCache struct {
+		Enabled          bool
+		TTL              int64   `mapstructure:"ttl" yaml:"ttl"`
+		QuantizationStep float64 `mapstructure:"quantization_step" yaml:"quantization_step"`
+	} `mapstructure:"search_cache" yaml:"search_cache"`
+
+	Catalog productmeta.Config
+
+	// Alerts configures the job checking favorited products for price drops and restocks every Interval seconds.
+	Alerts struct {
+		Enabled   bool
+		Interval  int64
+		BatchSize int `mapstructure:"batch_size" yaml:"batch_size"`
+	}
+
+	Notifications notify.Config
+
+	Mail mail.Config
+
+	PasswordReset struct {
+		// TokenExpire is how long a reset token is valid, in seconds.
+		TokenExpire int64 `mapstructure:"token_expire" yaml:"token_expire"`
+		// Url is the page the reset link in the email opens, the token replaces its {token} placeholder.
+		Url string
+	} `mapstructure:"password_reset" yaml:"password_reset"`
+
+	Sms sms.Config
+
+	Verification verification.Config
+
+	Otp otp.Config
+
+	Oidc oidc.Config
+
+	LoginGuard loginguard.Config `mapstructure:"login_guard" yaml:"login_guard"`
+
+	Captcha captcha.Config
+
+	TwoFactor twofactor.Config `mapstructure:"two_factor" yaml:"two_factor"`
+
+	// Guests are deleted along with their data once they haven't used the app for Expire seconds.
+	Guests struct {
+		Expire int64
+	}
+
 	S3 struct {
 		Endpoint  string
 		AccessKey string `mapstructure:"access_key" yaml:"access_key"`
@@ -67,5 +120,88 @@ func (c *Config) Validate() error {
 		"milvus.metricType":     validation.Validate(c.Milvus.MetricType, validation.Required),
 		"milvus.nProbe":         validation.Validate(c.Milvus.NProbe, validation.Required),
 		"milvus.collectionName": validation.Validate(c.Milvus.CollectionName, validation.Required),
+		"catalog.backend": validation.Validate(c.Catalog.Backend, validation.Required, validation.In(
+			productmeta.BackendDigikala, productmeta.BackendHttp, productmeta.BackendPostgres,
+		)),
+		"catalog.concurrency": validation.Validate(c.Catalog.Concurrency, validation.Required),
+		"catalog.upstream.rate": validation.Validate(c.Catalog.Upstream.Rate,
+			validation.When(c.Catalog.Backend != productmeta.BackendPostgres, validation.Required)),
+		"catalog.upstream.burst": validation.Validate(c.Catalog.Upstream.Burst,
+			validation.When(c.Catalog.Backend != productmeta.BackendPostgres, validation.Required)),
+		"catalog.upstream.min_concurrency": validation.Validate(c.Catalog.Upstream.MinConcurrency,
+			validation.When(c.Catalog.Backend != productmeta.BackendPostgres, validation.Required)),
+		"catalog.upstream.max_concurrency": validation.Validate(c.Catalog.Upstream.MaxConcurrency,
+			validation.When(c.Catalog.Backend != productmeta.BackendPostgres,
+				validation.Required, validation.Min(c.Catalog.Upstream.MinConcurrency))),
+		"catalog.http.product_url": validation.Validate(c.Catalog.Http.ProductUrl,
+			validation.When(c.Catalog.Backend == productmeta.BackendHttp, validation.Required)),
+		"catalog.mirror.sync_interval": validation.Validate(c.Catalog.Mirror.SyncInterval,
+			validation.When(c.Catalog.Mirror.Enabled, validation.Required)),
+		"catalog.price_history.sync_interval": validation.Validate(c.Catalog.PriceHistory.SyncInterval,
+			validation.When(c.Catalog.PriceHistory.Enabled, validation.Required)),
+		"catalog.price_history.batch_size": validation.Validate(c.Catalog.PriceHistory.BatchSize,
+			validation.When(c.Catalog.PriceHistory.Enabled, validation.Required)),
+		"alerts.interval": validation.Validate(c.Alerts.Interval, validation.When(c.Alerts.Enabled, validation.Required)),
+		"alerts.batch_size": validation.Validate(c.Alerts.BatchSize,
+			validation.When(c.Alerts.Enabled, validation.Required)),
+		"mail.backend": validation.Validate(c.Mail.Backend, validation.Required, validation.In(
+			mail.BackendSMTP, mail.BackendLog, mail.BackendFile,
+		)),
+		"mail.from": validation.Validate(c.Mail.From, validation.When(c.Mail.Backend != mail.BackendLog, validation.Required)),
+		"mail.smtp.host": validation.Validate(c.Mail.SMTP.Host,
+			validation.When(c.Mail.Backend == mail.BackendSMTP, validation.Required)),
+		"mail.dir": validation.Validate(c.Mail.Dir,
+			validation.When(c.Mail.Backend == mail.BackendFile, validation.Required)),
+		"password_reset.token_expire": validation.Validate(c.PasswordReset.TokenExpire, validation.Required),
+		"password_reset.url":          validation.Validate(c.PasswordReset.Url, validation.Required),
+		"sms.backend": validation.Validate(c.Sms.Backend, validation.Required, validation.In(
+			sms.BackendLog, sms.BackendHttp,
+		)),
+		"sms.http.url": validation.Validate(c.Sms.Http.Url,
+			validation.When(c.Sms.Backend == sms.BackendHttp, validation.Required)),
+		"verification.code_expire":  validation.Validate(c.Verification.CodeExpire, validation.Required),
+		"verification.max_attempts": validation.Validate(c.Verification.MaxAttempts, validation.Required),
+		"verification.required_for": validation.Validate(c.Verification.RequiredFor, validation.Each(validation.In(
+			verification.FeatureFavorites, verification.FeatureHistory,
+		))),
+		"otp.code_expire":  validation.Validate(c.Otp.CodeExpire, validation.Required),
+		"otp.max_attempts": validation.Validate(c.Otp.MaxAttempts, validation.Required),
+		"oidc.providers":   validation.Validate(c.Oidc.Providers, validation.Each(validation.By(validateOidcProvider))),
+		"oidc.state_expire": validation.Validate(c.Oidc.StateExpire,
+			validation.When(len(c.Oidc.Providers) > 0, validation.Required)),
+		"jwt.algorithm": validation.Validate(c.JWT.Algorithm, validation.Required, validation.In(
+			token.AlgRS256, token.AlgEdDSA,
+		)),
+		"jwt.encryption_key": validation.Validate(c.JWT.EncryptionKey,
+			validation.Required, validation.By(cfg_utils.ValidateEncryptionKey)),
+		"jwt.rotation_interval": validation.Validate(c.JWT.RotationInterval, validation.Required),
+		"jwt.publish_ahead": validation.Validate(c.JWT.PublishAhead,
+			validation.Max(c.JWT.RotationInterval).Exclusive()),
+		"login_guard.failure_window": validation.Validate(c.LoginGuard.FailureWindow, validation.Required),
+		"login_guard.lockout_base":   validation.Validate(c.LoginGuard.LockoutBase, validation.Required),
+		"login_guard.lockout_max": validation.Validate(c.LoginGuard.LockoutMax,
+			validation.Required, validation.Min(c.LoginGuard.LockoutBase)),
+		"captcha.backend": validation.Validate(c.Captcha.Backend, validation.In(captcha.BackendNone, captcha.BackendHttp)),
+		"captcha.url": validation.Validate(c.Captcha.Url,
+			validation.When(c.Captcha.Backend == captcha.BackendHttp, validation.Required)),
+		"two_factor.encryption_key": validation.Validate(c.TwoFactor.EncryptionKey,
+			validation.Required, validation.By(cfg_utils.ValidateEncryptionKey)),
+		"two_factor.challenge_expire": validation.Validate(c.TwoFactor.ChallengeExpire, validation.Required),
+		"two_factor.recovery_codes":   validation.Validate(c.TwoFactor.RecoveryCodes, validation.Required),
+		"guests.expire":               validation.Validate(c.Guests.Expire, validation.Required),
+		"notifications.webhook.url": validation.Validate(c.Notifications.Webhook.Url,
+			validation.When(c.Notifications.Webhook.Enabled, validation.Required)),
+		"search_cache.quantization_step": validation.Validate(c.SearchCache.QuantizationStep,
+			validation.When(c.SearchCache.Enabled, validation.Required, validation.Min(0.0).Exclusive())),
 	}.Filter()
 }
+
+func validateOidcProvider(value interface{}) error {
+	p := value.(oidc.ProviderConfig)
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required),
+		validation.Field(&p.Issuer, validation.Required),
+		validation.Field(&p.ClientID, validation.Required),
+		validation.Field(&p.RedirectUrl, validation.Required),
+	)
+}
@@ -1,5 +1,19 @@
 package cfg_utils
 
+import (
+	"encoding/base64"
+	"errors"
+)
+
 type Config interface {
 	Validate() error
 }
+
+// ValidateEncryptionKey checks that an encryption key is 32 base64 encoded bytes, for AES-256.
+func ValidateEncryptionKey(value interface{}) error {
+	key, err := base64.StdEncoding.DecodeString(value.(string))
+	if err != nil || len(key) != 32 {
+		return errors.New("must be 32 base64 encoded bytes")
+	}
+	return nil
+}
@@ -36,6 +36,68 @@ func newViper(prefix, configPath string, config Config) (*viper.Viper, error) {
 	v.SetDefault("milvus.metricType", entity.L2)
 	v.SetDefault("milvus.nProbe", 16)
 	v.SetDefault("milvus.collectionName", "products_revis_digikala_clip_ViT_L_14_336px")
+	v.SetDefault("catalog.backend", "digikala")
+	v.SetDefault("catalog.base_url", "https://www.digikala.com")
+	v.SetDefault("catalog.api_base_url", "https://api.digikala.com/v1/product")
+	v.SetDefault("catalog.max_retry", 3)
+	v.SetDefault("catalog.concurrency", 5)
+	v.SetDefault("catalog.upstream.rate", 20)
+	v.SetDefault("catalog.upstream.burst", 10)
+	v.SetDefault("catalog.upstream.min_concurrency", 1)
+	v.SetDefault("catalog.upstream.max_concurrency", 10)
+	v.SetDefault("catalog.upstream.backoff_base", 200)
+	v.SetDefault("catalog.upstream.backoff_max", 5000)
+	v.SetDefault("catalog.local_cache.size", 10000)
+	v.SetDefault("catalog.local_cache.ttl", 60)
+	v.SetDefault("catalog.cache.details_ttl", 86400)
+	v.SetDefault("catalog.cache.offer_ttl", 900)
+	v.SetDefault("catalog.cache.stale_ttl", 3600)
+	v.SetDefault("catalog.cache.negative_ttl", 3600)
+	v.SetDefault("catalog.mirror.max_age", 86400)
+	v.SetDefault("catalog.mirror.sync_interval", 60)
+	v.SetDefault("catalog.mirror.batch_size", 100)
+	v.SetDefault("catalog.price_history.min_interval", 3600)
+	v.SetDefault("catalog.price_history.sync_interval", 21600)
+	v.SetDefault("catalog.price_history.batch_size", 100)
+	v.SetDefault("alerts.interval", 900)
+	v.SetDefault("alerts.batch_size", 100)
+	v.SetDefault("mail.backend", "log")
+	v.SetDefault("mail.smtp.port", 25)
+	v.SetDefault("mail.dir", "mails")
+	v.SetDefault("password_reset.token_expire", 3600)
+	v.SetDefault("sms.backend", "log")
+	v.SetDefault("sms.http.timeout", 10)
+	v.SetDefault("verification.code_expire", 600)
+	v.SetDefault("verification.max_attempts", 5)
+	v.SetDefault("verification.resend_interval", 60)
+	v.SetDefault("otp.code_expire", 120)
+	v.SetDefault("otp.max_attempts", 5)
+	v.SetDefault("otp.resend_interval", 60)
+	v.SetDefault("otp.max_per_hour", 5)
+	v.SetDefault("oidc.state_expire", 600)
+	v.SetDefault("login_guard.failure_window", 900)
+	v.SetDefault("login_guard.max_failures", 5)
+	v.SetDefault("login_guard.ip_max_failures", 50)
+	v.SetDefault("login_guard.captcha_after", 3)
+	v.SetDefault("login_guard.lockout_base", 60)
+	v.SetDefault("login_guard.lockout_max", 3600)
+	v.SetDefault("login_guard.register_per_hour", 10)
+	v.SetDefault("login_guard.guest_per_hour", 30)
+	v.SetDefault("login_guard.reset_per_hour", 3)
+	v.SetDefault("login_guard.reset_ip_per_hour", 20)
+	v.SetDefault("login_guard.otp_per_hour", 20)
+	v.SetDefault("captcha.backend", "none")
+	v.SetDefault("captcha.timeout", 10)
+	v.SetDefault("two_factor.issuer", "Digivision")
+	v.SetDefault("two_factor.challenge_expire", 300)
+	v.SetDefault("two_factor.recovery_codes", 10)
+	v.SetDefault("guests.expire", 2592000)
+	v.SetDefault("jwt.algorithm", "RS256")
+	v.SetDefault("jwt.rotation_interval", 2592000)
+	v.SetDefault("jwt.publish_ahead", 86400)
+	v.SetDefault("notifications.webhook.timeout", 10)
+	v.SetDefault("search_cache.ttl", 3600)
+	v.SetDefault("search_cache.quantization_step", 0.01)
 
 	v.SetConfigType("yaml")
 	v.SetEnvPrefix(prefix)
@@ -12,4 +12,10 @@ var (
 	Internal           = status.Error(codes.Internal, "Internal error")
 	NotLoggedIn        = status.Error(codes.Unauthenticated, "You are not logged in")
 	NotFound           = status.Error(codes.NotFound, "Not found")
+	NotVerified        = status.Error(codes.PermissionDenied, "Verify your email address or phone number first")
+	PermissionDenied   = status.Error(codes.PermissionDenied, "You are not allowed to do this")
+	AccountDisabled    = status.Error(codes.PermissionDenied, "Your account is disabled")
+	InvalidApiKey      = status.Error(codes.Unauthenticated, "Invalid api key")
+	RateLimited        = status.Error(codes.ResourceExhausted, "Too many requests")
+	Unavailable        = status.Error(codes.Unavailable, "Service unavailable, try again later")
 )
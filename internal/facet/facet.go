@@ -0,0 +1,125 @@
+package facet
+
+import (
+	"math"
+	"net/url"
+	"sort"
+
+	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+)
+
+const maxPriceBuckets = 5
+
+// ratingBands are the lower bounds of the rating bands, on the 0 to 100 scale of product rates.
+var ratingBands = []int32{80, 60, 40, 0}
+
+// Compute returns the facets of products: counts per top-level category, a price histogram and rating bands.
+func Compute(products []*pb.Product) *pb.Facets {
+	return &pb.Facets{
+		Categories: categories(products),
+		Prices:     prices(products),
+		Ratings:    ratings(products),
+	}
+}
+
+// topLevelCategory returns the first category of a breadcrumb that is not the home page of the catalog.
+func topLevelCategory(product *pb.Product) *pb.Category {
+	for _, category := range product.Categories {
+		u, err := url.Parse(category.Url)
+		if err == nil && (u.Path == "" || u.Path == "/") {
+			continue
+		}
+		return category
+	}
+	return nil
+}
+
+// categories counts the products per top-level category, most frequent first.
+func categories(products []*pb.Product) []*pb.CategoryFacet {
+	facets := make([]*pb.CategoryFacet, 0)
+	index := make(map[string]*pb.CategoryFacet)
+	for _, product := range products {
+		category := topLevelCategory(product)
+		if category == nil {
+			continue
+		}
+		facet, ok := index[category.Url]
+		if !ok {
+			facet = &pb.CategoryFacet{Title: category.Title, Url: category.Url}
+			index[category.Url] = facet
+			facets = append(facets, facet)
+		}
+		facet.Count++
+	}
+	sort.SliceStable(facets, func(i, j int) bool {
+		return facets[i].Count > facets[j].Count
+	})
+	return facets
+}
+
+// prices builds a histogram of at most maxPriceBuckets buckets whose width is a round number.
+func prices(products []*pb.Product) []*pb.PriceBucket {
+	buckets := make([]*pb.PriceBucket, 0)
+	var min, max int64 = math.MaxInt64, 0
+	for _, product := range products {
+		if product.Price <= 0 {
+			continue
+		}
+		if product.Price < min {
+			min = product.Price
+		}
+		if product.Price > max {
+			max = product.Price
+		}
+	}
+	if max == 0 {
+		return buckets
+	}
+	width := roundUp((max - min + maxPriceBuckets) / maxPriceBuckets)
+	for max/width-min/width+1 > maxPriceBuckets {
+		width = roundUp(width + 1)
+	}
+	start := min / width * width
+	for bound := start; bound <= max; bound += width {
+		buckets = append(buckets, &pb.PriceBucket{Min: bound, Max: bound + width})
+	}
+	for _, product := range products {
+		if product.Price <= 0 {
+			continue
+		}
+		buckets[(product.Price-start)/width].Count++
+	}
+	return buckets
+}
+
+// roundUp returns the smallest number of the form 1, 2 or 5 times a power of ten not less than n.
+func roundUp(n int64) int64 {
+	for unit := int64(1); ; unit *= 10 {
+		for _, m := range []int64{1, 2, 5} {
+			if m*unit >= n {
+				return m * unit
+			}
+		}
+	}
+}
+
+func ratings(products []*pb.Product) []*pb.RatingBand {
+	bands := make([]*pb.RatingBand, len(ratingBands))
+	upper := int32(100)
+	for i, lower := range ratingBands {
+		bands[i] = &pb.RatingBand{Min: lower, Max: upper}
+		upper = lower
+	}
+	for _, product := range products {
+		if product.Rate == nil || product.Rate.Count == 0 {
+			continue
+		}
+		for _, band := range bands {
+			if product.Rate.Rate >= band.Min {
+				band.Count++
+				break
+			}
+		}
+	}
+	return bands
+}
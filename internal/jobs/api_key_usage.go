@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/apikey"
+)
+
+// apiKeyUsageInterval is how often ApiKeyUsageJob moves the usage of api keys to postgres.
+const apiKeyUsageInterval = time.Minute
+
+// ApiKeyUsageJob periodically moves the usage of api keys counted in redis to postgres.
+type ApiKeyUsageJob struct {
+	apiKeys *apikey.Manager
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewApiKeyUsageJob(apiKeys *apikey.Manager) *ApiKeyUsageJob {
+	return &ApiKeyUsageJob{
+		apiKeys: apiKeys,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (j *ApiKeyUsageJob) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(apiKeyUsageInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				j.flush()
+				return
+			case <-ticker.C:
+				j.flush()
+			}
+		}
+	}()
+}
+
+func (j *ApiKeyUsageJob) flush() {
+	if err := j.apiKeys.FlushUsage(context.Background()); err != nil {
+		logrus.Error("failed to flush api key usage: ", err)
+	}
+}
+
+func (j *ApiKeyUsageJob) Shutdown(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
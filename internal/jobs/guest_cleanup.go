@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/s3"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+const (
+	// guestCleanupInterval is how often GuestCleanupJob looks for expired guests.
+	guestCleanupInterval = time.Hour
+	guestCleanupBatch    = 100
+)
+
+// GuestCleanupJob deletes guests who haven't used the app for a while, along with their data and the query images
+// of their search history.
+type GuestCleanupJob struct {
+	storage  *storage.Storage
+	s3Client s3.Client
+	expire   time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewGuestCleanupJob(store *storage.Storage, s3Client s3.Client, expire time.Duration) *GuestCleanupJob {
+	return &GuestCleanupJob{
+		storage:  store,
+		s3Client: s3Client,
+		expire:   expire,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (j *GuestCleanupJob) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(guestCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.run()
+			}
+		}
+	}()
+}
+
+func (j *GuestCleanupJob) run() {
+	ctx := context.Background()
+	for {
+		ids, err := j.storage.GetInactiveGuests(time.Now().Add(-j.expire), guestCleanupBatch)
+		if err != nil {
+			logrus.Error("failed to get inactive guests: ", err)
+			return
+		}
+		for _, id := range ids {
+			if err := j.deleteGuest(ctx, id); err != nil {
+				logrus.Errorf("failed to delete guest %d: %v", id, err)
+				return
+			}
+		}
+		if len(ids) < guestCleanupBatch {
+			return
+		}
+		select {
+		case <-j.stop:
+			return
+		default:
+		}
+	}
+}
+
+func (j *GuestCleanupJob) deleteGuest(ctx context.Context, id uint) error {
+	paths, err := j.storage.GetSearchHistoryImages(id)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := j.s3Client.Remove(ctx, s3.HistoryImagesBucket, path); err != nil {
+			return err
+		}
+	}
+	return j.storage.DeleteUser(id)
+}
+
+func (j *GuestCleanupJob) Shutdown(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,11 +1,99 @@
 package jobs
 
 import (
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/apikey"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/bootstrap/job"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/cfg"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/notify"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/productmeta"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/s3"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
 )
 
 func StartJobs(config cfg.Config) []job.WithGracefulShutdown {
-	// TODO: Instantiate your job here and add those that need graceful shutdown to the return value.
-	return []job.WithGracefulShutdown{}
+	var jobs []job.WithGracefulShutdown
+
+	store := storage.NewStorage(&config.MainDB)
+
+	keys, err := token.NewKeySet(store, config.JWT)
+	if err != nil {
+		logrus.Fatal("failed to create signing key set: ", err)
+	}
+	keyRotationJob := NewKeyRotationJob(keys)
+	keyRotationJob.Start()
+	jobs = append(jobs, keyRotationJob)
+
+	s3Client, err := s3.NewMinioClient(config.S3.Endpoint, config.S3.AccessKey, config.S3.SecretKey, config.S3.UseSSL)
+	if err != nil {
+		logrus.Fatal("failed to create s3 client: ", err)
+	}
+	guestCleanupJob := NewGuestCleanupJob(store, s3Client, time.Duration(config.Guests.Expire)*time.Second)
+	guestCleanupJob.Start()
+	jobs = append(jobs, guestCleanupJob)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: config.Redis.Addr,
+	})
+	apiKeyUsageJob := NewApiKeyUsageJob(apikey.NewManager(store, rdb))
+	apiKeyUsageJob.Start()
+	jobs = append(jobs, apiKeyUsageJob)
+
+	mirror := config.Catalog.Backend == productmeta.BackendDigikala && config.Catalog.Mirror.Enabled
+	priceHistory := config.Catalog.Backend != productmeta.BackendPostgres && config.Catalog.PriceHistory.Enabled
+	if !mirror && !priceHistory && !config.Alerts.Enabled {
+		return jobs
+	}
+
+	fetcher, err := productmeta.NewFetcher(config.Catalog, resty.New(), rdb, store)
+	if err != nil {
+		logrus.Fatal("failed to create product fetcher: ", err)
+	}
+
+	if mirror {
+		syncJob := NewProductSyncJob(
+			fetcher.(productRefresher),
+			store,
+			time.Duration(config.Catalog.Mirror.SyncInterval)*time.Second,
+			time.Duration(config.Catalog.Mirror.MaxAge)*time.Second,
+			config.Catalog.Mirror.BatchSize,
+		)
+		syncJob.Start()
+		jobs = append(jobs, syncJob)
+	}
+
+	if priceHistory {
+		priceJob := NewPriceHistoryJob(
+			fetcher.(productRefresher),
+			store,
+			time.Duration(config.Catalog.PriceHistory.SyncInterval)*time.Second,
+			config.Catalog.PriceHistory.BatchSize,
+		)
+		priceJob.Start()
+		jobs = append(jobs, priceJob)
+	}
+
+	if config.Alerts.Enabled {
+		mailer, err := mail.NewMailer(config.Mail)
+		if err != nil {
+			logrus.Fatal("failed to create mailer: ", err)
+		}
+		alertJob := NewPriceAlertJob(
+			fetcher,
+			store,
+			notify.NewNotifier(config.Notifications, store, mailer),
+			time.Duration(config.Alerts.Interval)*time.Second,
+			config.Alerts.BatchSize,
+		)
+		alertJob.Start()
+		jobs = append(jobs, alertJob)
+	}
+
+	return jobs
 }
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
+)
+
+// keyRotationInterval is how often KeyRotationJob checks whether a new signing key is due.
+const keyRotationInterval = time.Hour
+
+// KeyRotationJob adds new token signing keys as the current ones get old.
+type KeyRotationJob struct {
+	keys *token.KeySet
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewKeyRotationJob(keys *token.KeySet) *KeyRotationJob {
+	return &KeyRotationJob{
+		keys: keys,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (j *KeyRotationJob) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(keyRotationInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				if err := j.keys.Rotate(); err != nil {
+					logrus.Error("failed to rotate signing keys: ", err)
+				}
+			}
+		}
+	}()
+}
+
+func (j *KeyRotationJob) Shutdown(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/notify"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/productmeta"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+)
+
+// freshFetcher is implemented by the fetchers that cache products, to fetch one from the upstream catalog
+// regardless of the caches. The other fetchers return the current product from Fetch anyway.
+type freshFetcher interface {
+	FetchFresh(ctx context.Context, product rank.Product) (*v1.Product, error)
+}
+
+type alertStore interface {
+	GetFavoriteProductIDs(afterID uint, limit int) ([]uint, error)
+	GetFavoriteItemsByProductID(productID uint) ([]storage.FavoriteListItem, error)
+	UpdateFavoriteItemAlertState(item *storage.FavoriteListItem) error
+}
+
+// PriceAlertJob periodically re-fetches the favorited products and notifies their owners about price drops beyond
+// their threshold and about products that became available again.
+type PriceAlertJob struct {
+	fetcher   productmeta.Fetcher
+	storage   alertStore
+	notifier  notify.Notifier
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewPriceAlertJob(
+	fetcher productmeta.Fetcher,
+	store *storage.Storage,
+	notifier notify.Notifier,
+	interval time.Duration,
+	batchSize int,
+) *PriceAlertJob {
+	return &PriceAlertJob{
+		fetcher:   fetcher,
+		storage:   store,
+		notifier:  notifier,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (j *PriceAlertJob) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.checkFavorites()
+			}
+		}
+	}()
+}
+
+func (j *PriceAlertJob) checkFavorites() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-j.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	var lastID uint
+	for ctx.Err() == nil {
+		ids, err := j.storage.GetFavoriteProductIDs(lastID, j.batchSize)
+		if err != nil {
+			logrus.Error("failed to get favorite products: ", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return
+			}
+			j.checkProduct(ctx, id)
+		}
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// checkProduct compares the current state of a product with the state every favorite item of it was last checked
+// against, and notifies the owners whose alert conditions are met.
+func (j *PriceAlertJob) checkProduct(ctx context.Context, pid uint) {
+	product, err := j.fetch(ctx, pid)
+	available := err == nil && product.Price > 0
+	if err != nil && !errors.Is(err, productmeta.ErrNotFound) && !errors.Is(err, productmeta.ErrInactive) {
+		logrus.Errorf("failed to check alerts of product %d: %v", pid, err)
+		return
+	}
+	items, err := j.storage.GetFavoriteItemsByProductID(pid)
+	if err != nil {
+		logrus.Errorf("failed to get favorite items of product %d: %v", pid, err)
+		return
+	}
+	now := time.Now()
+	notified := make(map[string]bool)
+	for i := range items {
+		item := &items[i]
+		if item.CheckedAt != nil && available {
+			user := &item.FavoriteList.User
+			if !item.Available && item.NotifyBackInStock {
+				j.notify(ctx, notified, user, backInStockNotification(product))
+			} else if item.Available && priceDropped(item, product.Price) {
+				j.notify(ctx, notified, user, priceDropNotification(product, item.BaselinePrice))
+				item.BaselinePrice = product.Price
+			}
+		}
+		if available && (item.CheckedAt == nil || !item.Available || product.Price > item.BaselinePrice) {
+			item.BaselinePrice = product.Price
+		}
+		item.Available = available
+		item.CheckedAt = &now
+		if err := j.storage.UpdateFavoriteItemAlertState(item); err != nil {
+			logrus.Errorf("failed to update alert state of favorite item %d: %v", item.ID, err)
+		}
+	}
+}
+
+// fetch returns the current state of a product, bypassing the caches, so alerts don't lag behind the upstream.
+func (j *PriceAlertJob) fetch(ctx context.Context, pid uint) (*v1.Product, error) {
+	product := rank.Product{Id: strconv.Itoa(int(pid))}
+	if fetcher, ok := j.fetcher.(freshFetcher); ok {
+		return fetcher.FetchFresh(ctx, product)
+	}
+	return j.fetcher.Fetch(ctx, product)
+}
+
+// notify delivers a notification once per user and kind, even if the user has favorited the product more than once.
+func (j *PriceAlertJob) notify(
+	ctx context.Context, notified map[string]bool, user *storage.UserAccount, notification *storage.Notification,
+) {
+	key := fmt.Sprintf("%d:%s", user.ID, notification.Kind)
+	if notified[key] {
+		return
+	}
+	notified[key] = true
+	if err := j.notifier.Notify(ctx, user, notification); err != nil {
+		logrus.Errorf("failed to notify user %d about product %d: %v", user.ID, notification.ProductID, err)
+	}
+}
+
+func priceDropped(item *storage.FavoriteListItem, price int64) bool {
+	if item.PriceDropPercent <= 0 || item.BaselinePrice <= 0 {
+		return false
+	}
+	return price*100 <= item.BaselinePrice*int64(100-item.PriceDropPercent)
+}
+
+func priceDropNotification(product *v1.Product, oldPrice int64) *storage.Notification {
+	return &storage.Notification{
+		Kind:      storage.NotificationPriceDrop,
+		ProductID: uint(product.Id),
+		Title:     fmt.Sprintf("Price drop: %s", product.Title),
+		Body:      fmt.Sprintf("%s is now %d, down from %d.\n%s", product.Title, product.Price, oldPrice, product.Url),
+		OldPrice:  oldPrice,
+		NewPrice:  product.Price,
+	}
+}
+
+func backInStockNotification(product *v1.Product) *storage.Notification {
+	return &storage.Notification{
+		Kind:      storage.NotificationBackInStock,
+		ProductID: uint(product.Id),
+		Title:     fmt.Sprintf("Back in stock: %s", product.Title),
+		Body:      fmt.Sprintf("%s is available again for %d.\n%s", product.Title, product.Price, product.Url),
+		NewPrice:  product.Price,
+	}
+}
+
+func (j *PriceAlertJob) Shutdown(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,295 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/web-programming-fall-2022/digivision-backend/internal/productmeta"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"gorm.io/gorm"
+)
+
+func TestPriceDropped(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		percent  int32
+		baseline int64
+		price    int64
+		want     bool
+	}{
+		{"exactly the threshold", 10, 1000, 900, true},
+		{"beyond the threshold", 10, 1000, 500, true},
+		{"short of the threshold", 10, 1000, 901, false},
+		{"no change", 10, 1000, 1000, false},
+		{"price rise", 10, 1000, 1200, false},
+		{"threshold of an uneven baseline", 25, 999, 749, true},
+		{"short of the threshold of an uneven baseline", 25, 999, 750, false},
+		{"alerts disabled", 0, 1000, 100, false},
+		{"no baseline", 10, 0, 100, false},
+	} {
+		item := &storage.FavoriteListItem{PriceDropPercent: tc.percent, BaselinePrice: tc.baseline}
+		if got := priceDropped(item, tc.price); got != tc.want {
+			t.Errorf("%s: priceDropped(%d%% of %d, %d) = %v, want %v",
+				tc.name, tc.percent, tc.baseline, tc.price, got, tc.want)
+		}
+	}
+}
+
+func TestCheckProduct(t *testing.T) {
+	checkedAt := time.Now().Add(-time.Hour)
+	notFound := fmt.Errorf("product 1: %w", productmeta.ErrNotFound)
+	inactive := fmt.Errorf("product 1: %w", productmeta.ErrInactive)
+	for _, tc := range []struct {
+		name string
+		item storage.FavoriteListItem
+		// price is the current price of the product, err the error of fetching it instead
+		price int64
+		err   error
+		// wantKind is the kind of the notification sent, if any
+		wantKind      string
+		wantOldPrice  int64
+		wantBaseline  int64
+		wantAvailable bool
+	}{
+		{
+			name:          "first check",
+			item:          alertItem(10, true, 0, false, nil),
+			price:         1000,
+			wantBaseline:  1000,
+			wantAvailable: true,
+		},
+		{
+			name:          "price drop beyond the threshold",
+			item:          alertItem(10, true, 1000, true, &checkedAt),
+			price:         850,
+			wantKind:      storage.NotificationPriceDrop,
+			wantOldPrice:  1000,
+			wantBaseline:  850,
+			wantAvailable: true,
+		},
+		{
+			name:          "price drop short of the threshold",
+			item:          alertItem(10, true, 1000, true, &checkedAt),
+			price:         950,
+			wantBaseline:  1000,
+			wantAvailable: true,
+		},
+		{
+			name:          "price drop with alerts disabled",
+			item:          alertItem(0, true, 1000, true, &checkedAt),
+			price:         500,
+			wantBaseline:  1000,
+			wantAvailable: true,
+		},
+		{
+			name:          "price rise raises the baseline",
+			item:          alertItem(10, true, 1000, true, &checkedAt),
+			price:         1200,
+			wantBaseline:  1200,
+			wantAvailable: true,
+		},
+		{
+			name:          "back in stock",
+			item:          alertItem(10, true, 1000, false, &checkedAt),
+			price:         1100,
+			wantKind:      storage.NotificationBackInStock,
+			wantBaseline:  1100,
+			wantAvailable: true,
+		},
+		{
+			name:          "back in stock cheaper is no price drop",
+			item:          alertItem(10, true, 1000, false, &checkedAt),
+			price:         500,
+			wantKind:      storage.NotificationBackInStock,
+			wantBaseline:  500,
+			wantAvailable: true,
+		},
+		{
+			name:          "back in stock with alerts disabled",
+			item:          alertItem(10, false, 1000, false, &checkedAt),
+			price:         1100,
+			wantBaseline:  1100,
+			wantAvailable: true,
+		},
+		{
+			name:         "not found",
+			item:         alertItem(10, true, 1000, true, &checkedAt),
+			err:          notFound,
+			wantBaseline: 1000,
+		},
+		{
+			name:         "inactive",
+			item:         alertItem(10, true, 1000, true, &checkedAt),
+			err:          inactive,
+			wantBaseline: 1000,
+		},
+		{
+			name:         "out of stock",
+			item:         alertItem(10, true, 1000, true, &checkedAt),
+			price:        0,
+			wantBaseline: 1000,
+		},
+		{
+			name:          "still unavailable",
+			item:          alertItem(10, true, 1000, false, &checkedAt),
+			err:           notFound,
+			wantBaseline:  1000,
+			wantAvailable: false,
+		},
+	} {
+		store := &memoryAlertStore{items: []storage.FavoriteListItem{tc.item}}
+		notifier := &recordingNotifier{}
+		job := NewPriceAlertJob(&stubFetcher{price: tc.price, err: tc.err}, nil, notifier, time.Minute, 10)
+		job.storage = store
+
+		job.checkProduct(context.Background(), 1)
+
+		if tc.wantKind == "" && len(notifier.sent) != 0 {
+			t.Errorf("%s: sent %s, want no notification", tc.name, notifier.sent[0].Kind)
+		}
+		if tc.wantKind != "" {
+			if len(notifier.sent) != 1 {
+				t.Errorf("%s: sent %d notifications, want a %s notification", tc.name, len(notifier.sent), tc.wantKind)
+			} else if sent := notifier.sent[0]; sent.Kind != tc.wantKind || sent.OldPrice != tc.wantOldPrice ||
+				sent.NewPrice != tc.price {
+				t.Errorf("%s: sent %s from %d to %d, want %s from %d to %d", tc.name,
+					sent.Kind, sent.OldPrice, sent.NewPrice, tc.wantKind, tc.wantOldPrice, tc.price)
+			}
+		}
+		updated, ok := store.updated[tc.item.ID]
+		if !ok {
+			t.Errorf("%s: alert state was not updated", tc.name)
+			continue
+		}
+		if updated.BaselinePrice != tc.wantBaseline || updated.Available != tc.wantAvailable || updated.CheckedAt == nil {
+			t.Errorf("%s: state is baseline %d, available %v, checked at %v, want baseline %d, available %v",
+				tc.name, updated.BaselinePrice, updated.Available, updated.CheckedAt, tc.wantBaseline, tc.wantAvailable)
+		}
+	}
+}
+
+func TestCheckProductFetchError(t *testing.T) {
+	checkedAt := time.Now().Add(-time.Hour)
+	store := &memoryAlertStore{items: []storage.FavoriteListItem{alertItem(10, true, 1000, true, &checkedAt)}}
+	notifier := &recordingNotifier{}
+	job := NewPriceAlertJob(&stubFetcher{err: productmeta.ErrUpstream}, nil, notifier, time.Minute, 10)
+	job.storage = store
+
+	job.checkProduct(context.Background(), 1)
+
+	// a failing upstream says nothing about the product, so it must not look unavailable
+	if len(store.updated) != 0 || len(notifier.sent) != 0 {
+		t.Errorf("updated %d items and sent %d notifications, want none", len(store.updated), len(notifier.sent))
+	}
+}
+
+func TestCheckProductNotifiesUsersOnce(t *testing.T) {
+	checkedAt := time.Now().Add(-time.Hour)
+	// users 1 and 2 favorited the product twice each, user 2 once with a threshold the drop falls short of
+	items := []storage.FavoriteListItem{
+		alertItem(10, true, 1000, true, &checkedAt),
+		alertItem(20, true, 1000, true, &checkedAt),
+		alertItem(10, true, 1000, true, &checkedAt),
+		alertItem(50, true, 1000, true, &checkedAt),
+		alertItem(10, true, 1000, true, &checkedAt),
+	}
+	for i, userID := range []uint{1, 1, 2, 2, 3} {
+		items[i].ID = uint(i + 1)
+		items[i].FavoriteList.User.ID = userID
+	}
+	store := &memoryAlertStore{items: items}
+	notifier := &recordingNotifier{}
+	job := NewPriceAlertJob(&stubFetcher{price: 800}, nil, notifier, time.Minute, 10)
+	job.storage = store
+
+	job.checkProduct(context.Background(), 1)
+
+	got := make(map[uint]int)
+	for _, user := range notifier.users {
+		got[user]++
+	}
+	if len(got) != 3 || got[1] != 1 || got[2] != 1 || got[3] != 1 {
+		t.Errorf("notifications per user = %v, want one for each of users 1, 2 and 3", got)
+	}
+	// every item whose threshold was met starts over from the new price, even if its user was notified already
+	for id, want := range map[uint]int64{1: 800, 2: 800, 3: 800, 4: 1000, 5: 800} {
+		if got := store.updated[id].BaselinePrice; got != want {
+			t.Errorf("baseline of item %d = %d, want %d", id, got, want)
+		}
+	}
+}
+
+func alertItem(
+	priceDropPercent int32, notifyBackInStock bool, baseline int64, available bool, checkedAt *time.Time,
+) storage.FavoriteListItem {
+	return storage.FavoriteListItem{
+		Model:             gorm.Model{ID: 1},
+		FavoriteList:      storage.FavoriteList{User: storage.UserAccount{Model: gorm.Model{ID: 1}}},
+		ProductID:         1,
+		PriceDropPercent:  priceDropPercent,
+		NotifyBackInStock: notifyBackInStock,
+		BaselinePrice:     baseline,
+		Available:         available,
+		CheckedAt:         checkedAt,
+	}
+}
+
+// memoryAlertStore returns its items as the favorite items of every product and keeps the updated alert states.
+type memoryAlertStore struct {
+	items   []storage.FavoriteListItem
+	updated map[uint]storage.FavoriteListItem
+}
+
+func (s *memoryAlertStore) GetFavoriteProductIDs(afterID uint, limit int) ([]uint, error) {
+	return nil, nil
+}
+
+func (s *memoryAlertStore) GetFavoriteItemsByProductID(productID uint) ([]storage.FavoriteListItem, error) {
+	return append([]storage.FavoriteListItem(nil), s.items...), nil
+}
+
+func (s *memoryAlertStore) UpdateFavoriteItemAlertState(item *storage.FavoriteListItem) error {
+	if s.updated == nil {
+		s.updated = make(map[uint]storage.FavoriteListItem)
+	}
+	s.updated[item.ID] = *item
+	return nil
+}
+
+// stubFetcher returns a product of the price, or err.
+type stubFetcher struct {
+	price int64
+	err   error
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, product rank.Product) (*v1.Product, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &v1.Product{Id: 1, Title: "product", Price: f.price}, nil
+}
+
+func (f *stubFetcher) FetchDetails(ctx context.Context, pid int) (*v1.ProductDetails, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *stubFetcher) AsyncFetch(ctx context.Context, products []rank.Product, count int) chan *productmeta.ProductWithError {
+	return nil
+}
+
+// recordingNotifier keeps the notifications sent and the users they were sent to.
+type recordingNotifier struct {
+	sent  []*storage.Notification
+	users []uint
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, user *storage.UserAccount, notification *storage.Notification) error {
+	n.sent = append(n.sent, notification)
+	n.users = append(n.users, user.ID)
+	return nil
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// PriceHistoryJob periodically re-fetches the favorited products so their prices are recorded even if nobody
+// searches for them.
+type PriceHistoryJob struct {
+	refresher productRefresher
+	storage   *storage.Storage
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewPriceHistoryJob(
+	refresher productRefresher,
+	store *storage.Storage,
+	interval time.Duration,
+	batchSize int,
+) *PriceHistoryJob {
+	return &PriceHistoryJob{
+		refresher: refresher,
+		storage:   store,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (j *PriceHistoryJob) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.recordFavoritePrices()
+			}
+		}
+	}()
+}
+
+func (j *PriceHistoryJob) recordFavoritePrices() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-j.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	var lastID uint
+	refreshed, total := 0, 0
+	for ctx.Err() == nil {
+		ids, err := j.storage.GetFavoriteProductIDs(lastID, j.batchSize)
+		if err != nil {
+			logrus.Error("failed to get favorite products: ", err)
+			return
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := j.refresher.Refresh(ctx, id); err != nil {
+				logrus.Errorf("failed to record price of product %d: %v", id, err)
+				continue
+			}
+			refreshed++
+		}
+		total += len(ids)
+		lastID = ids[len(ids)-1]
+	}
+	logrus.Debugf("recorded prices of %d of %d favorite products", refreshed, total)
+}
+
+func (j *PriceHistoryJob) Shutdown(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
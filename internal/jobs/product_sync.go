@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+type productRefresher interface {
+	Refresh(ctx context.Context, pid uint) error
+}
+
+// ProductSyncJob periodically re-fetches the mirrored products that have become stale.
+type ProductSyncJob struct {
+	refresher productRefresher
+	storage   *storage.Storage
+	interval  time.Duration
+	maxAge    time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewProductSyncJob(
+	refresher productRefresher,
+	store *storage.Storage,
+	interval time.Duration,
+	maxAge time.Duration,
+	batchSize int,
+) *ProductSyncJob {
+	return &ProductSyncJob{
+		refresher: refresher,
+		storage:   store,
+		interval:  interval,
+		maxAge:    maxAge,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (j *ProductSyncJob) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.syncStaleProducts()
+			}
+		}
+	}()
+}
+
+func (j *ProductSyncJob) syncStaleProducts() {
+	products, err := j.storage.GetStaleProducts(time.Now().Add(-j.maxAge), j.batchSize)
+	if err != nil {
+		logrus.Error("failed to get stale products: ", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-j.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	synced := 0
+	for _, product := range products {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := j.refresher.Refresh(ctx, product.ID); err != nil {
+			logrus.Errorf("failed to sync product %d: %v", product.ID, err)
+			continue
+		}
+		synced++
+	}
+	logrus.Debugf("synced %d of %d stale products", synced, len(products))
+}
+
+func (j *ProductSyncJob) Shutdown(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,318 @@
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Config configures the protection of logins against password guessing. Failed logins are counted per account
+// and per ip over FailureWindow seconds. An account or ip with MaxFailures or IPMaxFailures failures is locked out
+// for LockoutBase seconds, doubling with every further lockout within a day up to LockoutMax. From CaptchaAfter
+// failures on, logins of the account need a captcha, if captchas are configured. An ip can register at most
+// RegisterPerHour users and start GuestPerHour guest sessions an hour. Password resets can be requested
+// ResetPerHour times an hour per email and ResetIPPerHour times per ip, and an ip can request OTPPerHour SMS codes
+// an hour, whatever the phone numbers.
+type Config struct {
+	FailureWindow   int64 `mapstructure:"failure_window" yaml:"failure_window"`
+	MaxFailures     int64 `mapstructure:"max_failures" yaml:"max_failures"`
+	IPMaxFailures   int64 `mapstructure:"ip_max_failures" yaml:"ip_max_failures"`
+	CaptchaAfter    int64 `mapstructure:"captcha_after" yaml:"captcha_after"`
+	LockoutBase     int64 `mapstructure:"lockout_base" yaml:"lockout_base"`
+	LockoutMax      int64 `mapstructure:"lockout_max" yaml:"lockout_max"`
+	RegisterPerHour int64 `mapstructure:"register_per_hour" yaml:"register_per_hour"`
+	GuestPerHour    int64 `mapstructure:"guest_per_hour" yaml:"guest_per_hour"`
+	ResetPerHour    int64 `mapstructure:"reset_per_hour" yaml:"reset_per_hour"`
+	ResetIPPerHour  int64 `mapstructure:"reset_ip_per_hour" yaml:"reset_ip_per_hour"`
+	OTPPerHour      int64 `mapstructure:"otp_per_hour" yaml:"otp_per_hour"`
+}
+
+// lockoutMemory is how long lockouts count towards the length of the next one.
+const lockoutMemory = 24 * time.Hour
+
+// State is what a login of an account from an ip has to deal with.
+type State struct {
+	// RetryAfter is set while the account or ip is locked out.
+	RetryAfter      time.Duration
+	CaptchaRequired bool
+}
+
+// Lockout is a lockout caused by a failed login.
+type Lockout struct {
+	// Scope is "account" or "ip".
+	Scope    string
+	Duration time.Duration
+	Failures int64
+}
+
+// Guard counts failed logins in redis and locks accounts and ips out. Redis errors never block logins.
+type Guard struct {
+	client *redis.Client
+	config Config
+}
+
+func NewGuard(client *redis.Client, config Config) *Guard {
+	return &Guard{
+		client: client,
+		config: config,
+	}
+}
+
+func failuresKey(scope string, id string) string {
+	return fmt.Sprintf("login:failures:%s:%s", scope, id)
+}
+
+func lockKey(scope string, id string) string {
+	return fmt.Sprintf("login:lock:%s:%s", scope, id)
+}
+
+func lockoutsKey(scope string, id string) string {
+	return fmt.Sprintf("login:lockouts:%s:%s", scope, id)
+}
+
+func registerKey(ip string, hour int64) string {
+	return fmt.Sprintf("register:ip:%s:%d", ip, hour)
+}
+
+func guestKey(ip string, hour int64) string {
+	return fmt.Sprintf("guest:ip:%s:%d", ip, hour)
+}
+
+func resetKey(email string, hour int64) string {
+	return fmt.Sprintf("reset:email:%s:%d", email, hour)
+}
+
+func resetIPKey(ip string, hour int64) string {
+	return fmt.Sprintf("reset:ip:%s:%d", ip, hour)
+}
+
+func otpKey(ip string, hour int64) string {
+	return fmt.Sprintf("otp:ip:%s:%d", ip, hour)
+}
+
+// normalize makes sure an account can't dodge its counter by changing the case of its email.
+func normalize(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// attemptScript reserves a login attempt of an account or ip: unless it is locked out (KEYS[1]) it counts the attempt
+// as a failure (KEYS[2]) right away, and takes it back if that makes more than ARGV[1] failures. Concurrent attempts
+// can't all pass before the first failures lock them out this way. It returns the remaining lockout in
+// milliseconds, the failures counted before the attempt and whether the attempt was rejected.
+var attemptScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	return {ttl, 0, 1}
+end
+local failures = redis.call("INCR", KEYS[2])
+if failures == 1 then
+	redis.call("EXPIRE", KEYS[2], ARGV[2])
+end
+local max = tonumber(ARGV[1])
+if max > 0 and failures > max then
+	redis.call("DECR", KEYS[2])
+	return {0, failures - 1, 1}
+end
+return {0, failures - 1, 0}
+`)
+
+// busyRetryAfter is how long to wait when an account or ip has as many attempts under way as it may still fail.
+const busyRetryAfter = time.Second
+
+// Attempt starts a login of an account from an ip and returns what it has to deal with. The attempt counts as a
+// failure until Succeed or Cancel takes it back, so a rejected attempt must not go on and every other attempt has
+// to end with Fail, Succeed or Cancel.
+func (g *Guard) Attempt(ctx context.Context, account string, ip string) State {
+	account = normalize(account)
+	var state State
+	var counted [][2]string
+	for _, a := range []struct {
+		scope       string
+		id          string
+		maxFailures int64
+	}{{"account", account, g.config.MaxFailures}, {"ip", ip, g.config.IPMaxFailures}} {
+		if a.id == "" {
+			continue
+		}
+		res, err := attemptScript.Run(ctx, g.client,
+			[]string{lockKey(a.scope, a.id), failuresKey(a.scope, a.id)},
+			a.maxFailures, g.config.FailureWindow,
+		).Int64Slice()
+		if err != nil {
+			logrus.Error("failed to count login attempt: ", err)
+			continue
+		}
+		ttl, failures, rejected := time.Duration(res[0])*time.Millisecond, res[1], res[2] == 1
+		if !rejected {
+			counted = append(counted, [2]string{a.scope, a.id})
+		} else if ttl < busyRetryAfter {
+			ttl = busyRetryAfter
+		}
+		if ttl > state.RetryAfter {
+			state.RetryAfter = ttl
+		}
+		if a.scope == "account" && g.config.CaptchaAfter > 0 {
+			state.CaptchaRequired = failures >= g.config.CaptchaAfter
+		}
+	}
+	if state.RetryAfter > 0 {
+		for _, c := range counted {
+			g.release(ctx, c[0], c[1])
+		}
+	}
+	return state
+}
+
+// Fail ends a failed login attempt of an account from an ip and returns the lockouts it caused.
+func (g *Guard) Fail(ctx context.Context, account string, ip string) []Lockout {
+	account = normalize(account)
+	var lockouts []Lockout
+	if lockout := g.fail(ctx, "account", account, g.config.MaxFailures); lockout != nil {
+		lockouts = append(lockouts, *lockout)
+	}
+	if ip != "" {
+		if lockout := g.fail(ctx, "ip", ip, g.config.IPMaxFailures); lockout != nil {
+			lockouts = append(lockouts, *lockout)
+		}
+	}
+	return lockouts
+}
+
+// fail locks an account or ip out once its failures, counted by Attempt, reach maxFailures. Only one of concurrent
+// failures locks it out, so the lockout isn't counted twice.
+func (g *Guard) fail(ctx context.Context, scope string, id string, maxFailures int64) *Lockout {
+	key := failuresKey(scope, id)
+	failures, err := g.client.Get(ctx, key).Int64()
+	if err != nil && err != redis.Nil {
+		logrus.Error("failed to get login failures: ", err)
+		return nil
+	}
+	if maxFailures <= 0 || failures < maxFailures {
+		return nil
+	}
+	locked, err := g.client.SetNX(ctx, lockKey(scope, id), 1, time.Duration(g.config.LockoutBase)*time.Second).Result()
+	if err != nil || !locked {
+		return nil
+	}
+	lockouts, err := g.client.Incr(ctx, lockoutsKey(scope, id)).Result()
+	if err != nil {
+		logrus.Error("failed to count lockout: ", err)
+		return nil
+	}
+	g.client.Expire(ctx, lockoutsKey(scope, id), lockoutMemory)
+	duration := g.lockoutDuration(lockouts)
+	g.client.Set(ctx, lockKey(scope, id), 1, duration)
+	// logins keep needing a captcha after the lockout, which ends after MaxFailures - CaptchaAfter more failures
+	if scope == "ip" {
+		g.client.Del(ctx, key)
+	} else {
+		g.client.Set(ctx, key, g.config.CaptchaAfter, time.Duration(g.config.FailureWindow)*time.Second)
+	}
+	return &Lockout{Scope: scope, Duration: duration, Failures: failures}
+}
+
+// lockoutDuration returns the length of the nth lockout: LockoutBase doubled for every earlier one, up to
+// LockoutMax.
+func (g *Guard) lockoutDuration(n int64) time.Duration {
+	duration := time.Duration(g.config.LockoutBase) * time.Second
+	max := time.Duration(g.config.LockoutMax) * time.Second
+	for i := int64(1); i < n && duration < max; i++ {
+		duration *= 2
+	}
+	if duration > max {
+		duration = max
+	}
+	return duration
+}
+
+// Succeed ends a successful login attempt of an account from an ip. It resets the failures and lockouts of the
+// account and takes the attempt back from the failures of the ip.
+func (g *Guard) Succeed(ctx context.Context, account string, ip string) {
+	account = normalize(account)
+	err := g.client.Del(ctx, failuresKey("account", account), lockoutsKey("account", account)).Err()
+	if err != nil {
+		logrus.Error("failed to reset login failures: ", err)
+	}
+	g.ReleaseIP(ctx, ip)
+}
+
+// Cancel ends a login attempt that didn't get to check the credentials, e.g. for a missing captcha, taking it back
+// from the failures of the account and the ip.
+func (g *Guard) Cancel(ctx context.Context, account string, ip string) {
+	g.release(ctx, "account", normalize(account))
+	g.ReleaseIP(ctx, ip)
+}
+
+// ReleaseIP takes a login attempt back from the failures of an ip, e.g. once the password was right but the account
+// still needs a second factor.
+func (g *Guard) ReleaseIP(ctx context.Context, ip string) {
+	g.release(ctx, "ip", ip)
+}
+
+func (g *Guard) release(ctx context.Context, scope string, id string) {
+	if id == "" {
+		return
+	}
+	key := failuresKey(scope, id)
+	failures, err := g.client.Decr(ctx, key).Result()
+	if err != nil {
+		logrus.Error("failed to release login attempt: ", err)
+		return
+	}
+	if failures <= 0 {
+		g.client.Del(ctx, key)
+	}
+}
+
+// AllowRegister counts a registration from an ip and returns how long to wait if the ip registered too many users
+// this hour, or zero.
+func (g *Guard) AllowRegister(ctx context.Context, ip string) time.Duration {
+	return g.allowPerHour(ctx, ip, g.config.RegisterPerHour, registerKey)
+}
+
+// AllowGuest is AllowRegister for guest sessions.
+func (g *Guard) AllowGuest(ctx context.Context, ip string) time.Duration {
+	return g.allowPerHour(ctx, ip, g.config.GuestPerHour, guestKey)
+}
+
+// AllowOTP is AllowRegister for SMS codes.
+func (g *Guard) AllowOTP(ctx context.Context, ip string) time.Duration {
+	return g.allowPerHour(ctx, ip, g.config.OTPPerHour, otpKey)
+}
+
+// AllowPasswordReset counts a password reset request for an email from an ip and returns how long to wait if either
+// requested too many resets this hour, or zero.
+func (g *Guard) AllowPasswordReset(ctx context.Context, email string, ip string) time.Duration {
+	wait := g.allowPerHour(ctx, normalize(email), g.config.ResetPerHour, resetKey)
+	if ipWait := g.allowPerHour(ctx, ip, g.config.ResetIPPerHour, resetIPKey); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// allowPerHour counts a request of id, e.g. an ip, and returns how long to wait if it made more than limit requests
+// this hour, or zero.
+func (g *Guard) allowPerHour(ctx context.Context, id string, limit int64, key func(string, int64) string) time.Duration {
+	if limit <= 0 || id == "" {
+		return 0
+	}
+	now := time.Now()
+	hour := now.Unix() / 3600
+	counter := key(id, hour)
+	count, err := g.client.Incr(ctx, counter).Result()
+	if err != nil {
+		logrus.Error("failed to count request: ", err)
+		return 0
+	}
+	if count == 1 {
+		g.client.Expire(ctx, counter, time.Hour)
+	}
+	if count > limit {
+		return time.Unix((hour+1)*3600, 0).Sub(now)
+	}
+	return 0
+}
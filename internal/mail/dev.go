@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// LogMailer logs emails instead of sending them.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, to string, subject string, body string) error {
+	logrus.WithFields(logrus.Fields{
+		"to":      to,
+		"subject": subject,
+	}).Info("mail: ", body)
+	return nil
+}
+
+// FileMailer writes every email to its own .eml file in a directory instead of sending it.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) FileMailer {
+	return FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m FileMailer) Send(_ context.Context, to string, subject string, body string) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(m.dir, name), message(m.from, to, subject, body), 0o644)
+}
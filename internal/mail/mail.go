@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	BackendSMTP = "smtp"
+	BackendLog  = "log"
+	BackendFile = "file"
+)
+
+// Mailer sends plain text emails.
+type Mailer interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
+
+// Config selects and configures the Mailer. The log and file backends are meant for local development: they log the
+// emails or write them to Dir instead of sending them.
+type Config struct {
+	Backend string
+	From    string
+	SMTP    SMTPConfig `mapstructure:"smtp" yaml:"smtp"`
+	Dir     string
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// NewMailer returns the Mailer of the backend selected by config.
+func NewMailer(config Config) (Mailer, error) {
+	switch config.Backend {
+	case BackendSMTP:
+		return NewSMTPMailer(config.SMTP, config.From), nil
+	case BackendLog:
+		return LogMailer{}, nil
+	case BackendFile:
+		return NewFileMailer(config.Dir, config.From), nil
+	}
+	return nil, fmt.Errorf("unknown mail backend %q", config.Backend)
+}
@@ -0,0 +1,19 @@
+package notify
+
+// Config configures the channels notifications are delivered through besides the in-app inbox. Emails are sent
+// through the mailer of the application.
+type Config struct {
+	Email struct {
+		Enabled bool
+	}
+	Webhook WebhookConfig
+}
+
+// WebhookConfig configures posting notifications as JSON to Url. If Secret is set, the body is signed with it and
+// the hex encoded HMAC-SHA256 is sent in the X-Signature header.
+type WebhookConfig struct {
+	Enabled bool
+	Url     string
+	Secret  string
+	Timeout int64
+}
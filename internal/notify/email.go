@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// EmailNotifier emails notifications to users.
+type EmailNotifier struct {
+	mailer mail.Mailer
+}
+
+func NewEmailNotifier(mailer mail.Mailer) EmailNotifier {
+	return EmailNotifier{mailer: mailer}
+}
+
+func (n EmailNotifier) Notify(ctx context.Context, user *storage.UserAccount, notification *storage.Notification) error {
+	if user.Email == "" {
+		return nil
+	}
+	return n.mailer.Send(ctx, user.Email, notification.Title, notification.Body)
+}
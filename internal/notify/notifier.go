@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// Notifier delivers a notification to a user.
+type Notifier interface {
+	Notify(ctx context.Context, user *storage.UserAccount, notification *storage.Notification) error
+}
+
+// MultiNotifier delivers notifications through all of its notifiers. A failing notifier does not stop the others.
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, user *storage.UserAccount, notification *storage.Notification) error {
+	var firstErr error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, user, notification); err != nil {
+			logrus.Errorf("failed to notify user %d. err: %s", user.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// InboxNotifier stores notifications in the in-app inbox of users.
+type InboxNotifier struct {
+	storage *storage.Storage
+}
+
+func NewInboxNotifier(store *storage.Storage) InboxNotifier {
+	return InboxNotifier{storage: store}
+}
+
+func (n InboxNotifier) Notify(_ context.Context, user *storage.UserAccount, notification *storage.Notification) error {
+	notification.UserID = user.ID
+	return n.storage.CreateNotification(notification)
+}
+
+// NewNotifier returns the inbox notifier along with the email and webhook notifiers enabled by config.
+func NewNotifier(config Config, store *storage.Storage, mailer mail.Mailer) Notifier {
+	notifiers := MultiNotifier{NewInboxNotifier(store)}
+	if config.Email.Enabled {
+		notifiers = append(notifiers, NewEmailNotifier(mailer))
+	}
+	if config.Webhook.Enabled {
+		notifiers = append(notifiers, NewWebhookNotifier(config.Webhook))
+	}
+	return notifiers
+}
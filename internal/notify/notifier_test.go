@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	testUser         = &storage.UserAccount{Model: gorm.Model{ID: 7}, Email: "user@example.com"}
+	testNotification = &storage.Notification{
+		Kind:      storage.NotificationPriceDrop,
+		ProductID: 42,
+		Title:     "Price drop: product",
+		Body:      "product is now 900, down from 1000.",
+		OldPrice:  1000,
+		NewPrice:  900,
+	}
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		secret string
+		status int
+		// wantErr is whether Notify fails
+		wantErr bool
+	}{
+		{"signed", "secret", http.StatusOK, false},
+		{"unsigned", "", http.StatusNoContent, false},
+		{"failing endpoint", "secret", http.StatusInternalServerError, true},
+	} {
+		var body []byte
+		var signature, contentType string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			signature = r.Header.Get("X-Signature")
+			contentType = r.Header.Get("Content-Type")
+			w.WriteHeader(tc.status)
+		}))
+		n := NewWebhookNotifier(WebhookConfig{Enabled: true, Url: srv.URL, Secret: tc.secret, Timeout: 5})
+
+		err := n.Notify(context.Background(), testUser, testNotification)
+		srv.Close()
+
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: Notify = %v, want error %v", tc.name, err, tc.wantErr)
+		}
+		if contentType != "application/json" {
+			t.Errorf("%s: content type = %q, want application/json", tc.name, contentType)
+		}
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("%s: body %q is no payload: %v", tc.name, body, err)
+			continue
+		}
+		want := webhookPayload{
+			UserID:    7,
+			Email:     "user@example.com",
+			Kind:      storage.NotificationPriceDrop,
+			ProductID: 42,
+			Title:     testNotification.Title,
+			Body:      testNotification.Body,
+			OldPrice:  1000,
+			NewPrice:  900,
+		}
+		if payload != want {
+			t.Errorf("%s: payload = %+v, want %+v", tc.name, payload, want)
+		}
+		wantSignature := ""
+		if tc.secret != "" {
+			mac := hmac.New(sha256.New, []byte(tc.secret))
+			mac.Write(body)
+			wantSignature = hex.EncodeToString(mac.Sum(nil))
+		}
+		if signature != wantSignature {
+			t.Errorf("%s: signature = %q, want %q", tc.name, signature, wantSignature)
+		}
+	}
+}
+
+func TestMultiNotifier(t *testing.T) {
+	errFailed := errors.New("failed")
+	first := &fakeNotifier{err: errFailed}
+	second := &fakeNotifier{}
+	third := &fakeNotifier{err: errors.New("failed too")}
+
+	err := MultiNotifier{first, second, third}.Notify(context.Background(), testUser, testNotification)
+
+	if err != errFailed {
+		t.Errorf("Notify = %v, want the error of the first failing notifier", err)
+	}
+	for i, n := range []*fakeNotifier{first, second, third} {
+		if len(n.sent) != 1 || n.sent[0] != testNotification {
+			t.Errorf("notifier %d got %d notifications, want the notification once", i, len(n.sent))
+		}
+	}
+}
+
+// fakeNotifier keeps the notifications it's given and fails with err.
+type fakeNotifier struct {
+	sent []*storage.Notification
+	err  error
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, _ *storage.UserAccount, notification *storage.Notification) error {
+	n.sent = append(n.sent, notification)
+	return n.err
+}
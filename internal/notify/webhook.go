@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+type webhookPayload struct {
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Kind      string `json:"kind"`
+	ProductID uint   `json:"product_id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	OldPrice  int64  `json:"old_price"`
+	NewPrice  int64  `json:"new_price"`
+}
+
+// WebhookNotifier posts notifications to an http endpoint.
+type WebhookNotifier struct {
+	url    string
+	secret []byte
+	client *resty.Client
+}
+
+func NewWebhookNotifier(config WebhookConfig) WebhookNotifier {
+	return WebhookNotifier{
+		url:    config.Url,
+		secret: []byte(config.Secret),
+		client: resty.New().SetTimeout(time.Duration(config.Timeout) * time.Second),
+	}
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, user *storage.UserAccount, notification *storage.Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Kind:      notification.Kind,
+		ProductID: notification.ProductID,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		OldPrice:  notification.OldPrice,
+		NewPrice:  notification.NewPrice,
+	})
+	if err != nil {
+		return err
+	}
+	req := n.client.R().SetContext(ctx).SetHeader("Content-Type", "application/json").SetBody(body)
+	if len(n.secret) != 0 {
+		mac := hmac.New(sha256.New, n.secret)
+		mac.Write(body)
+		req.SetHeader("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := req.Post(n.url)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("webhook responded with %s", resp.Status())
+	}
+	return nil
+}
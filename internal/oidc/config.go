@@ -0,0 +1,20 @@
+package oidc
+
+// Config lists the OpenID Connect providers users can log in with. A login has to finish within StateExpire seconds
+// of starting.
+type Config struct {
+	Providers   []ProviderConfig
+	StateExpire int64 `mapstructure:"state_expire" yaml:"state_expire"`
+}
+
+// ProviderConfig configures a provider, e.g. google with issuer https://accounts.google.com. Its endpoints and keys
+// are discovered from the issuer. RedirectUrl is the page of the frontend the provider redirects to, which passes
+// the code and state on to FinishOIDCLogin.
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string `mapstructure:"client_id" yaml:"client_id"`
+	ClientSecret string `mapstructure:"client_secret" yaml:"client_secret"`
+	RedirectUrl  string `mapstructure:"redirect_url" yaml:"redirect_url"`
+	Scopes       []string
+}
@@ -0,0 +1,128 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrInvalidState    = errors.New("invalid or expired login state")
+)
+
+// loginState is kept in redis between starting and finishing a login. UserID is the user who started the login, or
+// zero if nobody was logged in.
+type loginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	UserID       uint   `json:"user_id"`
+}
+
+// Manager runs authorization code logins with PKCE against the configured providers.
+type Manager struct {
+	providers   map[string]*Provider
+	client      *redis.Client
+	stateExpire time.Duration
+}
+
+func NewManager(config Config, httpClient *resty.Client, redisClient *redis.Client) *Manager {
+	providers := make(map[string]*Provider)
+	for _, p := range config.Providers {
+		providers[p.Name] = NewProvider(p, httpClient)
+	}
+	return &Manager{
+		providers:   providers,
+		client:      redisClient,
+		stateExpire: time.Duration(config.StateExpire) * time.Second,
+	}
+}
+
+func stateKey(state string) string {
+	return fmt.Sprintf("oidc:state:%s", state)
+}
+
+// Start begins a login of a user, or zero if nobody is logged in, with a provider and returns the url to send the
+// user to and the state it comes back with.
+func (m *Manager) Start(ctx context.Context, providerName string, userID uint) (string, string, error) {
+	provider, ok := m.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authUrl, err := provider.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+	val, err := json.Marshal(loginState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		UserID:       userID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if err := m.client.Set(ctx, stateKey(state), val, m.stateExpire).Err(); err != nil {
+		return "", "", err
+	}
+	return authUrl, state, nil
+}
+
+// Finish completes a login with the code and state the provider redirected the user with, and returns the claims
+// of the verified id token. Every state can be used once, and only by the user who started the login, so nobody
+// can get their provider account linked to someone else by making them finish a login.
+func (m *Manager) Finish(
+	ctx context.Context, providerName string, code string, state string, userID uint,
+) (*Claims, error) {
+	provider, ok := m.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	val, err := m.client.GetDel(ctx, stateKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s loginState
+	if err := json.Unmarshal(val, &s); err != nil || s.Provider != providerName || s.UserID != userID {
+		return nil, ErrInvalidState
+	}
+	rawIDToken, err := provider.Exchange(ctx, code, s.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Verify(ctx, rawIDToken, s.Nonce)
+}
+
+// randomString returns 32 random bytes encoded as base64url, long enough for a PKCE code verifier.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
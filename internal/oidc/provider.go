@@ -0,0 +1,281 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+// jwksRefreshInterval limits how often the keys of a provider are fetched again for an unknown key id.
+const jwksRefreshInterval = time.Minute
+
+// supportedAlgs are the signing algorithms accepted for id tokens.
+var supportedAlgs = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// Claims are the claims of a verified id token used to find or create the user.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Provider talks to an OpenID Connect provider. Its discovery document and keys are fetched on first use and cached.
+type Provider struct {
+	config ProviderConfig
+	client *resty.Client
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewProvider(config ProviderConfig, client *resty.Client) *Provider {
+	return &Provider{
+		config: config,
+		client: client,
+	}
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	var m metadata
+	resp, err := p.client.R().SetContext(ctx).SetResult(&m).
+		Get(strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch the discovery document")
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("discovery document request responded with %s", resp.Status())
+	}
+	if m.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("discovery document is of issuer %q instead of %q", m.Issuer, p.config.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JwksUri == "" {
+		return nil, errors.New("discovery document misses endpoints")
+	}
+	p.metadata = &m
+	return p.metadata, nil
+}
+
+// AuthCodeURL returns the url to send the user to for logging in, with a PKCE (S256) challenge.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := append([]string{"openid"}, p.config.Scopes...)
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectUrl},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return m.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the id token.
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		IDToken string `json:"id_token"`
+	}
+	resp, err := p.client.R().SetContext(ctx).SetResult(&result).SetFormData(map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  p.config.RedirectUrl,
+		"client_id":     p.config.ClientID,
+		"client_secret": p.config.ClientSecret,
+		"code_verifier": codeVerifier,
+	}).Post(m.TokenEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to exchange the code")
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("token endpoint responded with %s", resp.Status())
+	}
+	if result.IDToken == "" {
+		return "", errors.New("token endpoint returned no id token")
+	}
+	return result.IDToken, nil
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an id token and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (*Claims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parser := jwt.NewParser(jwt.WithValidMethods(supportedAlgs))
+	token, err := parser.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, m.JwksUri, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid id token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid id token claims")
+	}
+	if !claims.VerifyIssuer(p.config.Issuer, true) {
+		return nil, errors.New("id token is of another issuer")
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("id token is for another client")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("id token has no expiry")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.GivenName, _ = claims["given_name"].(string)
+	result.FamilyName, _ = claims["family_name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		// some providers send the boolean as a string
+		result.EmailVerified = verified == "true"
+	}
+	if result.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	return result, nil
+}
+
+// key returns the public key with the given id, fetching the keys again if it is unknown, since providers rotate
+// them.
+func (p *Provider) key(ctx context.Context, jwksUri string, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	resp, err := p.client.R().SetContext(ctx).SetResult(&jwks).Get(jwksUri)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch keys")
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("jwks request responded with %s", resp.Status())
+	}
+	keys := make(map[string]interface{})
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,316 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testClientID = "client"
+	testNonce    = "nonce"
+)
+
+func TestVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider()
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		// edit changes the claims of a valid id token
+		edit func(claims jwt.MapClaims)
+		// kid and key sign the token, the key of the issuer if key is nil
+		kid     string
+		key     interface{}
+		want    *Claims
+		wantErr string
+	}{
+		{
+			name: "valid",
+			want: &Claims{Subject: "subject", Email: "user@example.com", EmailVerified: true, GivenName: "Given"},
+		},
+		{
+			name: "audience list",
+			edit: func(claims jwt.MapClaims) { claims["aud"] = []string{"another client", testClientID} },
+			want: &Claims{Subject: "subject", Email: "user@example.com", EmailVerified: true, GivenName: "Given"},
+		},
+		{
+			name: "email_verified as a string",
+			edit: func(claims jwt.MapClaims) { claims["email_verified"] = "true" },
+			want: &Claims{Subject: "subject", Email: "user@example.com", EmailVerified: true, GivenName: "Given"},
+		},
+		{
+			name: "email_verified false as a string",
+			edit: func(claims jwt.MapClaims) { claims["email_verified"] = "false" },
+			want: &Claims{Subject: "subject", Email: "user@example.com", GivenName: "Given"},
+		},
+		{
+			name: "no email_verified",
+			edit: func(claims jwt.MapClaims) { delete(claims, "email_verified") },
+			want: &Claims{Subject: "subject", Email: "user@example.com", GivenName: "Given"},
+		},
+		{
+			name:    "issuer mismatch",
+			edit:    func(claims jwt.MapClaims) { claims["iss"] = "https://issuer.example.com" },
+			wantErr: "another issuer",
+		},
+		{
+			name:    "wrong audience",
+			edit:    func(claims jwt.MapClaims) { claims["aud"] = "another client" },
+			wantErr: "another client",
+		},
+		{
+			name:    "nonce mismatch",
+			edit:    func(claims jwt.MapClaims) { claims["nonce"] = "another nonce" },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "no nonce",
+			edit:    func(claims jwt.MapClaims) { delete(claims, "nonce") },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "expired",
+			edit:    func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr: "expired",
+		},
+		{
+			name:    "no expiry",
+			edit:    func(claims jwt.MapClaims) { delete(claims, "exp") },
+			wantErr: "no expiry",
+		},
+		{
+			name:    "no subject",
+			edit:    func(claims jwt.MapClaims) { delete(claims, "sub") },
+			wantErr: "no subject",
+		},
+		{
+			name:    "signed by another key of the same id",
+			key:     other,
+			wantErr: "invalid id token",
+		},
+		{
+			name:    "unsupported algorithm",
+			key:     []byte("secret"),
+			wantErr: "invalid id token",
+		},
+	} {
+		claims := issuer.claims()
+		if tc.edit != nil {
+			tc.edit(claims)
+		}
+		key := tc.key
+		if key == nil {
+			key = issuer.key
+		}
+		got, err := p.Verify(context.Background(), signIDToken(t, claims, "k1", key), testNonce)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: Verify = %v, want an error containing %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Verify = %v, want nil", tc.name, err)
+			continue
+		}
+		if *got != *tc.want {
+			t.Errorf("%s: Verify = %+v, want %+v", tc.name, *got, *tc.want)
+		}
+	}
+}
+
+func TestVerifyDiscoveryIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.discoveredIssuer = "https://issuer.example.com"
+	p := issuer.provider()
+
+	_, err := p.Verify(context.Background(), signIDToken(t, issuer.claims(), "k1", issuer.key), testNonce)
+	if err == nil || !strings.Contains(err.Error(), "discovery document is of issuer") {
+		t.Errorf("Verify = %v, want a discovery document issuer error", err)
+	}
+	if _, err := p.AuthCodeURL(context.Background(), "state", testNonce, "challenge"); err == nil {
+		t.Error("AuthCodeURL = nil, want a discovery document issuer error")
+	}
+}
+
+func TestVerifyRefetchesKeysOfUnknownKid(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider()
+	if _, err := p.Verify(context.Background(), signIDToken(t, issuer.claims(), "k1", issuer.key), testNonce); err != nil {
+		t.Fatalf("Verify = %v, want nil", err)
+	}
+
+	// the provider rotates to an RSA key
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.mu.Lock()
+	issuer.jwks = append(issuer.jwks, rsaJWK("k2", &rotated.PublicKey))
+	issuer.mu.Unlock()
+	rotatedToken := signIDToken(t, issuer.claims(), "k2", rotated)
+
+	// keys were fetched just now, so an unknown kid doesn't fetch them again yet
+	if _, err := p.Verify(context.Background(), rotatedToken, testNonce); err == nil {
+		t.Error("Verify of a token of a new key right after fetching the keys = nil, want an unknown key error")
+	}
+	if fetches := issuer.jwksFetches(); fetches != 1 {
+		t.Errorf("keys were fetched %d times, want 1", fetches)
+	}
+
+	p.mu.Lock()
+	p.keysFetchedAt = time.Now().Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+	if _, err := p.Verify(context.Background(), rotatedToken, testNonce); err != nil {
+		t.Errorf("Verify of a token of a new key = %v, want nil", err)
+	}
+	if fetches := issuer.jwksFetches(); fetches != 2 {
+		t.Errorf("keys were fetched %d times, want 2", fetches)
+	}
+	// known keys don't fetch the keys again
+	if _, err := p.Verify(context.Background(), signIDToken(t, issuer.claims(), "k1", issuer.key), testNonce); err != nil {
+		t.Errorf("Verify of a token of the old key = %v, want nil", err)
+	}
+	if fetches := issuer.jwksFetches(); fetches != 2 {
+		t.Errorf("keys were fetched %d times, want 2", fetches)
+	}
+}
+
+// testIssuer serves the discovery document and the keys of a provider signing with the ES256 key k1.
+type testIssuer struct {
+	srv *httptest.Server
+	key *ecdsa.PrivateKey
+
+	mu sync.Mutex
+	// discoveredIssuer is the issuer of the discovery document, the url of the server if empty
+	discoveredIssuer string
+	jwks             []jsonWebKey
+	fetches          int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{key: key, jwks: []jsonWebKey{ecJWK("k1", &key.PublicKey)}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		discovered := issuer.discoveredIssuer
+		issuer.mu.Unlock()
+		if discovered == "" {
+			discovered = issuer.srv.URL
+		}
+		writeJSON(w, metadata{
+			Issuer:                discovered,
+			AuthorizationEndpoint: issuer.srv.URL + "/authorize",
+			TokenEndpoint:         issuer.srv.URL + "/token",
+			JwksUri:               issuer.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		issuer.fetches++
+		writeJSON(w, map[string][]jsonWebKey{"keys": issuer.jwks})
+	})
+	issuer.srv = httptest.NewServer(mux)
+	t.Cleanup(issuer.srv.Close)
+	return issuer
+}
+
+func (i *testIssuer) provider() *Provider {
+	return NewProvider(ProviderConfig{
+		Name:        "test",
+		Issuer:      i.srv.URL,
+		ClientID:    testClientID,
+		RedirectUrl: "https://app.example.com/oidc",
+	}, resty.New())
+}
+
+// claims returns the claims of a valid id token.
+func (i *testIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            i.srv.URL,
+		"aud":            testClientID,
+		"sub":            "subject",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          testNonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"given_name":     "Given",
+	}
+}
+
+func (i *testIssuer) jwksFetches() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.fetches
+}
+
+func signIDToken(t *testing.T, claims jwt.MapClaims, kid string, key interface{}) string {
+	t.Helper()
+	var method jwt.SigningMethod
+	switch key.(type) {
+	case *ecdsa.PrivateKey:
+		method = jwt.SigningMethodES256
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	default:
+		method = jwt.SigningMethodHS256
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Use: "sig",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
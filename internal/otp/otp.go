@@ -0,0 +1,146 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/sms"
+)
+
+var (
+	ErrTooSoon         = errors.New("a code was sent recently")
+	ErrTooManyRequests = errors.New("too many codes requested")
+	ErrNoCode          = errors.New("no active code")
+	ErrTooManyAttempts = errors.New("too many attempts")
+	ErrWrongCode       = errors.New("wrong code")
+)
+
+// Config configures login codes. Codes expire after CodeExpire seconds and can be tried MaxAttempts times. A phone
+// number gets a new code at most every ResendInterval seconds and at most MaxPerHour codes an hour.
+type Config struct {
+	CodeExpire     int64 `mapstructure:"code_expire" yaml:"code_expire"`
+	MaxAttempts    int64 `mapstructure:"max_attempts" yaml:"max_attempts"`
+	ResendInterval int64 `mapstructure:"resend_interval" yaml:"resend_interval"`
+	MaxPerHour     int64 `mapstructure:"max_per_hour" yaml:"max_per_hour"`
+}
+
+// Manager sends one time login codes to phone numbers and checks them. Codes live in redis, hashed, along with the
+// number of attempts to enter them.
+type Manager struct {
+	client *redis.Client
+	sms    sms.Sender
+	config Config
+}
+
+func NewManager(client *redis.Client, smsSender sms.Sender, config Config) *Manager {
+	return &Manager{
+		client: client,
+		sms:    smsSender,
+		config: config,
+	}
+}
+
+func codeKey(phoneNumber string) string {
+	return fmt.Sprintf("otp:code:%s", phoneNumber)
+}
+
+func resendKey(phoneNumber string) string {
+	return fmt.Sprintf("otp:resend:%s", phoneNumber)
+}
+
+func countKey(phoneNumber string) string {
+	return fmt.Sprintf("otp:count:%s", phoneNumber)
+}
+
+// Request sends a new code to the phone number, replacing the previous one.
+func (m *Manager) Request(ctx context.Context, phoneNumber string) error {
+	ok, err := m.client.SetNX(ctx, resendKey(phoneNumber), 1, time.Duration(m.config.ResendInterval)*time.Second).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTooSoon
+	}
+	count, err := m.client.Incr(ctx, countKey(phoneNumber)).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		m.client.Expire(ctx, countKey(phoneNumber), time.Hour)
+	}
+	if m.config.MaxPerHour > 0 && count > m.config.MaxPerHour {
+		return ErrTooManyRequests
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return err
+	}
+	key := codeKey(phoneNumber)
+	_, err = m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, "hash", hashCode(phoneNumber, code), "attempts", 0)
+		pipe.Expire(ctx, key, time.Duration(m.config.CodeExpire)*time.Second)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("Digivision login code: %s\nValid for %d minutes.", code, m.config.CodeExpire/60)
+	return m.sms.Send(ctx, phoneNumber, text)
+}
+
+// Verify checks a code sent to the phone number. A matching code is deleted, so it logs in once.
+func (m *Manager) Verify(ctx context.Context, phoneNumber string, code string) error {
+	key := codeKey(phoneNumber)
+	attempts, err := m.client.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return err
+	}
+	hash, err := m.client.HGet(ctx, key, "hash").Result()
+	if err == redis.Nil {
+		// HIncrBy created the key of a missing code
+		m.client.Del(ctx, key)
+		return ErrNoCode
+	}
+	if err != nil {
+		return err
+	}
+	if attempts > m.config.MaxAttempts {
+		m.client.Del(ctx, key)
+		return ErrTooManyAttempts
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(phoneNumber, code)), []byte(hash)) != 1 {
+		return ErrWrongCode
+	}
+	deleted, err := m.client.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNoCode
+	}
+	return nil
+}
+
+// generateCode returns a random 6 digit code.
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashCode(phoneNumber string, code string) string {
+	sum := sha256.Sum256([]byte(phoneNumber + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package productmeta
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+)
+
+type empty interface{}
+type semaphore chan empty
+
+type fetchFunc func(ctx context.Context, product rank.Product) (*v1.Product, error)
+
+// ProductWithError is the result of fetching a product. On failure, Reason holds the failure reason of Error.
+type ProductWithError struct {
+	Product *v1.Product
+	Error   error
+	// ProductID and Reason identify the failed product and why it failed when Error is not nil.
+	ProductID string
+	Reason    string
+}
+
+// asyncFetcher implements AsyncFetch on top of a single product fetch function. It is shared by all catalog
+// backends.
+type asyncFetcher struct {
+	fetch    fetchFunc
+	maxRetry int
+	backoff  backoff
+	// sem bounds the concurrent fetches, it is nil for backends whose upstreamGuard gates them.
+	sem semaphore
+}
+
+// newAsyncFetcher returns an asyncFetcher that runs at most concurrencyFactor fetches at once, or all of them if
+// it is zero. Backends with an upstreamGuard pass zero, so the guard can adapt the concurrency of their upstream
+// fetches.
+func newAsyncFetcher(fetch fetchFunc, maxRetry int, concurrencyFactor int, backoff backoff) asyncFetcher {
+	f := asyncFetcher{
+		fetch:    fetch,
+		maxRetry: maxRetry,
+		backoff:  backoff,
+	}
+	if concurrencyFactor > 0 {
+		f.sem = make(semaphore, concurrencyFactor)
+	}
+	return f
+}
+
+func (f asyncFetcher) AsyncFetch(ctx context.Context, products []rank.Product, count int) chan *ProductWithError {
+	resp := make(chan *ProductWithError)
+
+	responses := make([]chan *ProductWithError, len(products))
+	for i := range products {
+		responses[i] = make(chan *ProductWithError, 1)
+	}
+	innerCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		for i, product := range products {
+			select {
+			case <-innerCtx.Done():
+				return
+			default:
+				f.singleAsyncFetch(innerCtx, product, responses[i])
+			}
+		}
+	}()
+
+	go func() {
+		defer close(resp)
+		defer cancel()
+		c := 0
+		i := 0
+		for {
+			if c >= count || i >= len(products) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case p := <-responses[i]:
+				if p == nil {
+					return
+				}
+				i++
+				resp <- p
+				if p.Product != nil {
+					c++
+				}
+			}
+		}
+	}()
+
+	return resp
+}
+
+func (f asyncFetcher) singleAsyncFetch(ctx context.Context, product rank.Product, resp chan *ProductWithError) {
+	if f.sem != nil {
+		select {
+		case f.sem <- nil:
+		case <-ctx.Done():
+			resp <- failure(product, newFetchError(product.Id, ErrCanceled, ctx.Err()))
+			close(resp)
+			return
+		}
+	}
+	go func() {
+		defer func() {
+			if f.sem != nil {
+				<-f.sem
+			}
+			close(resp)
+		}()
+		p, e := f.fetch(ctx, product)
+		retryCount := 0
+		for e != nil {
+			if !retryable(e) {
+				resp <- failure(product, errors.Wrapf(e, "failed to fetch product %s", product.Id))
+				return
+			}
+			if retryCount >= f.maxRetry {
+				resp <- failure(product, errors.Wrapf(e, "failed to fetch product %s after %d retries", product.Id, retryCount))
+				return
+			}
+			var wait time.Duration
+			var fetchErr *FetchError
+			if errors.As(e, &fetchErr) {
+				wait = fetchErr.RetryAfter
+			}
+			select {
+			case <-ctx.Done():
+				resp <- failure(product, newFetchError(product.Id, ErrCanceled, ctx.Err()))
+				return
+			case <-time.After(f.backoff.delay(retryCount, wait)):
+			}
+			p, e = f.fetch(ctx, product)
+			retryCount++
+		}
+		resp <- &ProductWithError{
+			Product: p,
+			Error:   nil,
+		}
+	}()
+}
+
+func failure(product rank.Product, err error) *ProductWithError {
+	reason := Reason(err)
+	fetchFailures.WithLabelValues(reason).Inc()
+	logrus.WithFields(logrus.Fields{
+		"product_id": product.Id,
+		"reason":     reason,
+	}).Debug(err)
+	return &ProductWithError{
+		Product:   nil,
+		Error:     err,
+		ProductID: product.Id,
+		Reason:    reason,
+	}
+}
@@ -0,0 +1,181 @@
+package productmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+const refreshLockTTL = 30 * time.Second
+
+// CacheConfig configures how long fetched products are cached in redis, in seconds. Details (title, images,
+// rating, categories, brand and specs) and offers (prices, status and variants) expire independently. An expired
+// entry is still served for StaleTTL seconds while it is refreshed in the background. Products that do not exist or
+// are inactive are remembered for NegativeTTL seconds.
+type CacheConfig struct {
+	DetailsTTL  int64 `mapstructure:"details_ttl" yaml:"details_ttl"`
+	OfferTTL    int64 `mapstructure:"offer_ttl" yaml:"offer_ttl"`
+	StaleTTL    int64 `mapstructure:"stale_ttl" yaml:"stale_ttl"`
+	NegativeTTL int64 `mapstructure:"negative_ttl" yaml:"negative_ttl"`
+}
+
+type cachedDetails struct {
+	FetchedAt  time.Time                 `json:"fetched_at"`
+	Title      string                    `json:"title"`
+	Url        string                    `json:"url"`
+	ImageUrl   string                    `json:"image_url"`
+	Rate       int32                     `json:"rate"`
+	RateCount  int32                     `json:"rate_count"`
+	Categories []storage.ProductCategory `json:"categories"`
+	Brand      string                    `json:"brand"`
+	Images     []string                  `json:"images"`
+	Specs      []storage.ProductSpec     `json:"specs"`
+}
+
+type cachedOffer struct {
+	FetchedAt       time.Time                `json:"fetched_at"`
+	Status          string                   `json:"status"`
+	Price           int64                    `json:"price"`
+	IsInactive      bool                     `json:"is_inactive"`
+	RrpPrice        int64                    `json:"rrp_price"`
+	DiscountPercent int32                    `json:"discount_percent"`
+	SellerCount     int32                    `json:"seller_count"`
+	Variants        []storage.ProductVariant `json:"variants"`
+}
+
+// productCache stores products in redis, split into field classes with their own time to live.
+type productCache struct {
+	client      *redis.Client
+	detailsTTL  time.Duration
+	offerTTL    time.Duration
+	staleTTL    time.Duration
+	negativeTTL time.Duration
+}
+
+func newProductCache(client *redis.Client, config CacheConfig) productCache {
+	return productCache{
+		client:      client,
+		detailsTTL:  time.Duration(config.DetailsTTL) * time.Second,
+		offerTTL:    time.Duration(config.OfferTTL) * time.Second,
+		staleTTL:    time.Duration(config.StaleTTL) * time.Second,
+		negativeTTL: time.Duration(config.NegativeTTL) * time.Second,
+	}
+}
+
+// get returns the cached product and whether any of its field classes is past its time to live. It returns
+// redis.Nil if the product is not (completely) cached.
+func (c productCache) get(ctx context.Context, pid uint) (*storage.Product, bool, error) {
+	values, err := c.client.MGet(ctx, detailsKey(pid), offerKey(pid)).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	rawDetails, ok1 := values[0].(string)
+	rawOffer, ok2 := values[1].(string)
+	if !ok1 || !ok2 {
+		return nil, false, redis.Nil
+	}
+	details := cachedDetails{}
+	if err := json.Unmarshal([]byte(rawDetails), &details); err != nil {
+		return nil, false, err
+	}
+	offer := cachedOffer{}
+	if err := json.Unmarshal([]byte(rawOffer), &offer); err != nil {
+		return nil, false, err
+	}
+	product := &storage.Product{
+		Title:           details.Title,
+		Url:             details.Url,
+		Status:          offer.Status,
+		ImageUrl:        details.ImageUrl,
+		Rate:            details.Rate,
+		RateCount:       details.RateCount,
+		Price:           offer.Price,
+		IsInactive:      offer.IsInactive,
+		Categories:      details.Categories,
+		Brand:           details.Brand,
+		Images:          details.Images,
+		Specs:           details.Specs,
+		RrpPrice:        offer.RrpPrice,
+		DiscountPercent: offer.DiscountPercent,
+		SellerCount:     offer.SellerCount,
+		Variants:        offer.Variants,
+		SyncedAt:        offer.FetchedAt,
+	}
+	product.ID = pid
+	stale := time.Since(details.FetchedAt) > c.detailsTTL || time.Since(offer.FetchedAt) > c.offerTTL
+	return product, stale, nil
+}
+
+func (c productCache) set(ctx context.Context, p *storage.Product) {
+	details, _ := json.Marshal(cachedDetails{
+		FetchedAt:  p.SyncedAt,
+		Title:      p.Title,
+		Url:        p.Url,
+		ImageUrl:   p.ImageUrl,
+		Rate:       p.Rate,
+		RateCount:  p.RateCount,
+		Categories: p.Categories,
+		Brand:      p.Brand,
+		Images:     p.Images,
+		Specs:      p.Specs,
+	})
+	offer, _ := json.Marshal(cachedOffer{
+		FetchedAt:       p.SyncedAt,
+		Status:          p.Status,
+		Price:           p.Price,
+		IsInactive:      p.IsInactive,
+		RrpPrice:        p.RrpPrice,
+		DiscountPercent: p.DiscountPercent,
+		SellerCount:     p.SellerCount,
+		Variants:        p.Variants,
+	})
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, detailsKey(p.ID), details, c.detailsTTL+c.staleTTL)
+		pipe.Set(ctx, offerKey(p.ID), offer, c.offerTTL+c.staleTTL)
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("failed to set product %d to redis. err: %s", p.ID, err)
+	}
+}
+
+// getNegative returns the reason the product was remembered as unavailable, if any.
+func (c productCache) getNegative(ctx context.Context, pid uint) (string, bool) {
+	reason, err := c.client.Get(ctx, negativeKey(pid)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			logrus.Error("failed to read negative cache of product ", pid, " err: ", err)
+		}
+		return "", false
+	}
+	return reason, true
+}
+
+func (c productCache) setNegative(ctx context.Context, pid uint, reason string) {
+	if err := c.client.Set(ctx, negativeKey(pid), reason, c.negativeTTL).Err(); err != nil {
+		logrus.Errorf("failed to set negative cache of product %d. err: %s", pid, err)
+	}
+}
+
+// lockRefresh makes sure only one background refresh of a product runs at a time, across all replicas.
+func (c productCache) lockRefresh(ctx context.Context, pid uint) bool {
+	ok, err := c.client.SetNX(ctx, fmt.Sprintf("product:refresh:%d", pid), 1, refreshLockTTL).Result()
+	return err == nil && ok
+}
+
+func detailsKey(pid uint) string {
+	return fmt.Sprintf("product:details:%d", pid)
+}
+
+func offerKey(pid uint) string {
+	return fmt.Sprintf("product:offer:%d", pid)
+}
+
+func negativeKey(pid uint) string {
+	return fmt.Sprintf("product:negative:%d", pid)
+}
@@ -0,0 +1,63 @@
+package productmeta
+
+const (
+	BackendDigikala = "digikala"
+	BackendHttp     = "http"
+	BackendPostgres = "postgres"
+)
+
+// Config selects and configures the catalog backend used to fetch product metadata.
+type Config struct {
+	Backend    string
+	BaseUrl    string `mapstructure:"base_url" yaml:"base_url"`
+	ApiBaseUrl string `mapstructure:"api_base_url" yaml:"api_base_url"`
+	MaxRetry   int    `mapstructure:"max_retry" yaml:"max_retry"`
+	// Concurrency bounds the concurrent fetches of the postgres backend. The upstream backends are bounded by
+	// Upstream instead.
+	Concurrency int
+	Http        HttpConfig
+	Upstream    UpstreamConfig
+	LocalCache  LocalCacheConfig `mapstructure:"local_cache" yaml:"local_cache"`
+	Cache       CacheConfig
+	Mirror      MirrorConfig
+	// PriceHistory is not supported by the postgres backend whose prices never change.
+	PriceHistory PriceHistoryConfig `mapstructure:"price_history" yaml:"price_history"`
+}
+
+// MirrorConfig configures mirroring of digikala products into the local catalog. Mirrored products are served
+// while they are younger than MaxAge seconds; a background job re-syncs up to BatchSize stale products every
+// SyncInterval seconds.
+type MirrorConfig struct {
+	Enabled      bool
+	MaxAge       int64 `mapstructure:"max_age" yaml:"max_age"`
+	SyncInterval int64 `mapstructure:"sync_interval" yaml:"sync_interval"`
+	BatchSize    int   `mapstructure:"batch_size" yaml:"batch_size"`
+}
+
+// HttpConfig configures HttpJsonFetcher. ProductUrl may contain an {id} placeholder, otherwise the product id is
+// appended to it.
+type HttpConfig struct {
+	ProductUrl string       `mapstructure:"product_url" yaml:"product_url"`
+	Mapping    FieldMapping `mapstructure:"mapping" yaml:"mapping"`
+}
+
+// FieldMapping holds the paths (e.g. "data.product.images.main.url[0]") of product fields in an upstream JSON
+// response. Category paths are relative to the elements of the Categories array, ImageItemUrl to the elements of
+// the Images array. Brand, RrpPrice and Images are only used by product details.
+type FieldMapping struct {
+	Title         string
+	Url           string
+	Status        string
+	ImageUrl      string `mapstructure:"image_url" yaml:"image_url"`
+	Rate          string
+	RateCount     string `mapstructure:"rate_count" yaml:"rate_count"`
+	Price         string
+	Inactive      string
+	Categories    string
+	CategoryTitle string `mapstructure:"category_title" yaml:"category_title"`
+	CategoryUrl   string `mapstructure:"category_url" yaml:"category_url"`
+	Brand         string
+	RrpPrice      string `mapstructure:"rrp_price" yaml:"rrp_price"`
+	Images        string
+	ImageItemUrl  string `mapstructure:"image_item_url" yaml:"image_item_url"`
+}
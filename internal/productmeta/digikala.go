@@ -9,35 +9,50 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
 	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
-type empty interface{}
-type semaphore chan empty
+const revalidateTimeout = 10 * time.Second
 
 type DigikalaFetcher struct {
-	baseUrl     string
-	apiBaseUrl  string
-	client      *resty.Client
-	redisClient *redis.Client
-	maxRetry    int
-	sem         semaphore
+	asyncFetcher
+	baseUrl      string
+	apiBaseUrl   string
+	client       *resty.Client
+	upstream     upstreamGuard
+	local        localFetcher
+	cache        productCache
+	store        *storage.Storage
+	mirrorMaxAge time.Duration
+	prices       PriceRecorder
 }
 
+// NewDigikalaFetcher returns a new DigikalaFetcher. If store is not nil, products are mirrored into the local
+// catalog and served from it as long as they are not older than mirrorMaxAge. Mirrored and cached products whose
+// offer is older than the configured offer TTL are refreshed in the background. Prices of products fetched from
+// digikala are recorded by prices.
 func NewDigikalaFetcher(
-	baseUrl string, apiBaseUrl string, client *resty.Client, redisClient *redis.Client, maxRetry int, concurrencyFactor int,
+	baseUrl string, apiBaseUrl string, client *resty.Client, redisClient *redis.Client, maxRetry int,
+	upstreamConfig UpstreamConfig, localCacheConfig LocalCacheConfig, cacheConfig CacheConfig, store *storage.Storage, mirrorMaxAge time.Duration,
+	prices PriceRecorder,
 ) DigikalaFetcher {
-	return DigikalaFetcher{
-		baseUrl:     baseUrl,
-		apiBaseUrl:  apiBaseUrl,
-		client:      client,
-		redisClient: redisClient,
-		maxRetry:    maxRetry,
-		sem:         make(semaphore, concurrencyFactor),
+	f := DigikalaFetcher{
+		baseUrl:      baseUrl,
+		apiBaseUrl:   apiBaseUrl,
+		client:       client,
+		upstream:     newUpstreamGuard(upstreamConfig),
+		local:        newLocalFetcher(localCacheConfig),
+		cache:        newProductCache(redisClient, cacheConfig),
+		store:        store,
+		mirrorMaxAge: mirrorMaxAge,
+		prices:       prices,
 	}
+	f.asyncFetcher = newAsyncFetcher(f.Fetch, maxRetry, 0, newBackoff(upstreamConfig))
+	return f
 }
 
 func (f DigikalaFetcher) Fetch(ctx context.Context, product rank.Product) (*v1.Product, error) {
@@ -45,154 +60,160 @@ func (f DigikalaFetcher) Fetch(ctx context.Context, product rank.Product) (*v1.P
 	if err != nil {
 		return nil, err
 	}
-
-	cache, err := f.redisClient.Get(ctx, fmt.Sprintf("product:%d", pid)).Result()
-	dkProduct := DigikalaProduct{}
-	if err == redis.Nil {
-		err = f.fetchFromDigikala(ctx, product, &dkProduct)
-	} else if err != nil {
-		logrus.Error("failed to fetch product from redis ", pid, " err: ", err)
-		err = f.fetchFromDigikala(ctx, product, &dkProduct)
-	} else {
-		err = json.Unmarshal([]byte(cache), &dkProduct)
+	p, err := f.get(ctx, pid)
+	if err != nil {
+		return nil, err
 	}
+	result := FromStorageProduct(p)
+	result.Score = product.Score
+	return result, nil
+}
 
+func (f DigikalaFetcher) FetchDetails(ctx context.Context, pid int) (*v1.ProductDetails, error) {
+	p, err := f.get(ctx, pid)
 	if err != nil {
 		return nil, err
 	}
-	p := dkProduct.Data.Product
+	return FromStorageProductDetails(p), nil
+}
+
+func (f DigikalaFetcher) get(ctx context.Context, pid int) (*storage.Product, error) {
+	p, err := f.local.get(ctx, uint(pid), f.fetch)
+	if err != nil {
+		return nil, classify(pid, err)
+	}
 	if p.IsInactive {
-		return nil, fmt.Errorf("product %s is inactive", product.Id)
-	}
-	return &v1.Product{
-		Id:       int32(pid),
-		Title:    p.TitleFa,
-		Url:      fmt.Sprintf("%s%s", f.baseUrl, p.Url.Uri),
-		Status:   p.Status,
-		ImageUrl: p.Images.Main.Url[0],
-		Rate: &v1.Rating{
-			Rate:  p.Rating.Rate,
-			Count: p.Rating.Count,
-		},
-		Categories: ToCategories(f.baseUrl, p.Breadcrumb),
-		Price:      p.DefaultVariant.Price.SellingPrice,
-		Score:      product.Score,
-	}, nil
+		return nil, newFetchError(pid, ErrInactive, nil)
+	}
+	return p, nil
 }
 
-func (f DigikalaFetcher) fetchFromDigikala(ctx context.Context, product rank.Product, dkProduct *DigikalaProduct) error {
+func (f DigikalaFetcher) fetch(ctx context.Context, pid uint) (*storage.Product, error) {
+	if reason, ok := f.cache.getNegative(ctx, pid); ok {
+		if reason == ReasonInactive {
+			return nil, newFetchError(pid, ErrInactive, nil)
+		}
+		return nil, newFetchError(pid, ErrNotFound, nil)
+	}
+	if p := f.fromMirror(pid); p != nil {
+		return p, nil
+	}
+	return f.fromCacheOrDigikala(ctx, pid)
+}
+
+// Refresh fetches the product from digikala regardless of the cached and mirrored versions and updates both.
+func (f DigikalaFetcher) Refresh(ctx context.Context, pid uint) error {
+	_, err := f.fetchFromDigikala(ctx, pid)
+	f.local.forget(pid)
+	return err
+}
+
+// FetchFresh fetches the product from digikala like Refresh does and returns it, e.g. to check its current price.
+func (f DigikalaFetcher) FetchFresh(ctx context.Context, product rank.Product) (*v1.Product, error) {
 	pid, err := strconv.Atoi(product.Id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	url := fmt.Sprintf("%s/%d/", f.apiBaseUrl, pid)
-	resp, err := f.client.R().SetContext(ctx).Get(url)
+	p, err := f.fetchFromDigikala(ctx, uint(pid))
+	f.local.forget(uint(pid))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if resp.Status() != "200 OK" {
-		return fmt.Errorf("failed to fetch product %s. status: %s", product.Id, resp.Status())
+	if p.IsInactive {
+		return nil, newFetchError(pid, ErrInactive, nil)
 	}
-	body := resp.Body()
-	err = f.redisClient.Set(ctx, fmt.Sprintf("product:%d", pid), string(body), 0).Err()
-	if err != nil {
-		logrus.Errorf("failed to set product %d to redis. err: %s", pid, err)
+	result := FromStorageProduct(p)
+	result.Score = product.Score
+	return result, nil
+}
+
+func (f DigikalaFetcher) fromMirror(pid uint) *storage.Product {
+	if f.store == nil {
+		return nil
+	}
+	p, err := f.store.GetProductByID(pid)
+	if err != nil || time.Since(p.SyncedAt) > f.mirrorMaxAge {
+		return nil
+	}
+	if time.Since(p.SyncedAt) > f.cache.offerTTL {
+		f.revalidate(pid)
 	}
-	err = json.Unmarshal(body, &dkProduct)
-	return nil
+	return p
 }
 
-type ProductWithError struct {
-	Product *v1.Product
-	Error   error
+func (f DigikalaFetcher) mirror(p *storage.Product) {
+	if f.store == nil {
+		return
+	}
+	if err := f.store.UpsertProduct(p); err != nil {
+		logrus.Errorf("failed to mirror product %d. err: %s", p.ID, err)
+	}
 }
 
-func (f DigikalaFetcher) AsyncFetch(ctx context.Context, products []rank.Product, count int) chan *ProductWithError {
-	resp := make(chan *ProductWithError)
+// unmirror removes a product digikala doesn't have anymore from the mirror, so it isn't synced again.
+func (f DigikalaFetcher) unmirror(pid uint) {
+	if f.store == nil {
+		return
+	}
+	if err := f.store.DeleteProduct(pid); err != nil {
+		logrus.Errorf("failed to remove product %d from the mirror. err: %s", pid, err)
+	}
+}
 
-	responses := make([]chan *ProductWithError, len(products))
-	for i := range products {
-		responses[i] = make(chan *ProductWithError, 1)
+func (f DigikalaFetcher) fromCacheOrDigikala(ctx context.Context, pid uint) (*storage.Product, error) {
+	p, stale, err := f.cache.get(ctx, pid)
+	if err == redis.Nil {
+		return f.fetchFromDigikala(ctx, pid)
+	} else if err != nil {
+		logrus.Error("failed to fetch product from redis ", pid, " err: ", err)
+		return f.fetchFromDigikala(ctx, pid)
 	}
-	innerCtx, cancel := context.WithCancel(ctx)
-	go func() {
-		for i, product := range products {
-			select {
-			case <-innerCtx.Done():
-				return
-			default:
-				f.singleAsyncFetch(innerCtx, product, responses[i])
-			}
-		}
-	}()
+	if stale {
+		f.revalidate(pid)
+	}
+	return p, nil
+}
 
+// revalidate refreshes a stale cached product in the background.
+func (f DigikalaFetcher) revalidate(pid uint) {
 	go func() {
-		defer close(resp)
+		ctx, cancel := context.WithTimeout(context.Background(), revalidateTimeout)
 		defer cancel()
-		c := 0
-		i := 0
-		for {
-			if c >= count || i >= len(products) {
-				return
-			}
-			select {
-			case <-ctx.Done():
-				return
-			case p := <-responses[i]:
-				if p == nil {
-					return
-				}
-				i++
-				resp <- p
-				if p.Product != nil {
-					c++
-				}
-			}
+		if !f.cache.lockRefresh(ctx, pid) {
+			return
+		}
+		if _, err := f.fetchFromDigikala(ctx, pid); err != nil {
+			logrus.Errorf("failed to revalidate product %d. err: %s", pid, err)
 		}
 	}()
-
-	return resp
 }
 
-func (f DigikalaFetcher) singleAsyncFetch(ctx context.Context, product rank.Product, resp chan *ProductWithError) {
-	var emp empty
-	f.sem <- emp
-	go func() {
-		defer func() {
-			<-f.sem
-			close(resp)
-		}()
-		p, e := f.Fetch(ctx, product)
-		retryCount := 0
-		for e != nil {
-			if strings.HasSuffix(e.Error(), "is inactive") {
-				resp <- &ProductWithError{
-					Product: nil,
-					Error:   errors.Wrapf(e, "failed to fetch product %s", product.Id),
-				}
-				return
-			}
-			if strings.HasSuffix(e.Error(), "context canceled") {
-				resp <- &ProductWithError{
-					Product: nil,
-					Error:   errors.Wrapf(e, "failed to fetch product %s", product.Id),
-				}
-				return
-			}
-			if retryCount >= f.maxRetry {
-				resp <- &ProductWithError{
-					Product: nil,
-					Error:   errors.Wrapf(e, "failed to fetch product %s after %d retries", product.Id, retryCount),
-				}
-				return
-			}
-			time.Sleep(1 * time.Second)
-			p, e = f.Fetch(ctx, product)
-			retryCount++
-		}
-		resp <- &ProductWithError{
-			Product: p,
-			Error:   nil,
+func (f DigikalaFetcher) fetchFromDigikala(ctx context.Context, pid uint) (*storage.Product, error) {
+	url := fmt.Sprintf("%s/%d/", f.apiBaseUrl, pid)
+	resp, err := f.upstream.get(ctx, f.client, url)
+	if err != nil {
+		return nil, classify(pid, err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		err := statusError(pid, resp.StatusCode(), resp.Status(), retryAfter(resp))
+		if errors.Is(err, ErrNotFound) {
+			f.cache.setNegative(ctx, pid, ReasonNotFound)
+			f.unmirror(pid)
 		}
-	}()
+		return nil, err
+	}
+	dkProduct := DigikalaProduct{}
+	if err := json.Unmarshal(resp.Body(), &dkProduct); err != nil {
+		return nil, newFetchError(pid, ErrDecode, err)
+	}
+	p := dkProduct.ToStorageProduct(pid, f.baseUrl)
+	f.cache.set(ctx, p)
+	if p.IsInactive {
+		f.cache.setNegative(ctx, pid, ReasonInactive)
+	}
+	f.mirror(p)
+	if !p.IsInactive {
+		f.prices.record(p.ID, p.Price)
+	}
+	return p, nil
 }
@@ -0,0 +1,130 @@
+package productmeta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Kinds of fetch failures. Use errors.Is to check the kind of an error returned by a Fetcher.
+var (
+	ErrNotFound  = errors.New("product not found")
+	ErrInactive  = errors.New("product is inactive")
+	ErrThrottled = errors.New("upstream throttled")
+	ErrUpstream  = errors.New("upstream error")
+	ErrRejected  = errors.New("upstream rejected the request")
+	ErrDecode    = errors.New("could not decode product")
+	ErrCanceled  = errors.New("fetch canceled")
+)
+
+// Failure reasons reported in metrics, logs and search responses.
+const (
+	ReasonNotFound  = "not_found"
+	ReasonInactive  = "inactive"
+	ReasonThrottled = "upstream_throttled"
+	ReasonUpstream  = "upstream_5xx"
+	ReasonRejected  = "upstream_4xx"
+	ReasonDecode    = "decode_error"
+	ReasonCanceled  = "canceled"
+	ReasonUnknown   = "unknown"
+)
+
+var fetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "product_fetch_failures_total",
+	Help: "Number of products that could not be fetched, by reason.",
+}, []string{"reason"})
+
+// FetchError describes why a product could not be fetched.
+type FetchError struct {
+	ProductID string
+	Kind      error
+	// RetryAfter is the wait requested by a throttling upstream.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func newFetchError(productID interface{}, kind error, err error) *FetchError {
+	return &FetchError{
+		ProductID: fmt.Sprint(productID),
+		Kind:      kind,
+		Err:       err,
+	}
+}
+
+func (e *FetchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("product %s: %s: %s", e.ProductID, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("product %s: %s", e.ProductID, e.Kind)
+}
+
+func (e *FetchError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns the failure reason of a fetch error.
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ReasonNotFound
+	case errors.Is(err, ErrInactive):
+		return ReasonInactive
+	case errors.Is(err, ErrThrottled):
+		return ReasonThrottled
+	case errors.Is(err, ErrUpstream):
+		return ReasonUpstream
+	case errors.Is(err, ErrRejected):
+		return ReasonRejected
+	case errors.Is(err, ErrDecode):
+		return ReasonDecode
+	case errors.Is(err, ErrCanceled), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ReasonCanceled
+	}
+	return ReasonUnknown
+}
+
+// retryable tells whether fetching the product again may succeed.
+func retryable(err error) bool {
+	switch Reason(err) {
+	case ReasonNotFound, ReasonInactive, ReasonRejected, ReasonDecode, ReasonCanceled:
+		return false
+	}
+	return true
+}
+
+// classify turns errors of the underlying clients into a FetchError.
+func classify(productID interface{}, err error) error {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return newFetchError(productID, ErrCanceled, err)
+	}
+	return newFetchError(productID, ErrUpstream, err)
+}
+
+// statusError returns the FetchError for an unsuccessful upstream response status. Only server errors and
+// throttling are worth retrying; other statuses won't change on their own.
+func statusError(productID interface{}, statusCode int, status string, retryAfter time.Duration) error {
+	switch {
+	case statusCode == http.StatusNotFound:
+		return newFetchError(productID, ErrNotFound, nil)
+	case statusCode == http.StatusTooManyRequests:
+		err := newFetchError(productID, ErrThrottled, nil)
+		err.RetryAfter = retryAfter
+		return err
+	case statusCode >= 500:
+		return newFetchError(productID, ErrUpstream, fmt.Errorf("status: %s", status))
+	}
+	return newFetchError(productID, ErrRejected, fmt.Errorf("status: %s", status))
+}
@@ -2,11 +2,65 @@ package productmeta
 
 import (
 	"context"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
 	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"time"
 )
 
 type Fetcher interface {
 	Fetch(ctx context.Context, product rank.Product) (*v1.Product, error)
+	// FetchDetails returns the product with everything its detail sheet shows, e.g. gallery, variants and specs.
+	FetchDetails(ctx context.Context, pid int) (*v1.ProductDetails, error)
 	AsyncFetch(ctx context.Context, products []rank.Product, count int) chan *ProductWithError
 }
+
+// NewFetcher returns the Fetcher of the catalog backend selected by config.
+func NewFetcher(
+	config Config, client *resty.Client, redisClient *redis.Client, store *storage.Storage,
+) (Fetcher, error) {
+	switch config.Backend {
+	case BackendDigikala:
+		return NewDigikalaFetcherFromConfig(config, client, redisClient, store), nil
+	case BackendHttp:
+		return NewHttpJsonFetcher(
+			config.BaseUrl,
+			config.Http.ProductUrl,
+			config.Http.Mapping,
+			client,
+			config.MaxRetry,
+			config.Upstream,
+			NewPriceRecorder(config.PriceHistory, store),
+		), nil
+	case BackendPostgres:
+		return NewPostgresFetcher(store, config.Concurrency), nil
+	}
+	return nil, fmt.Errorf("unknown catalog backend %q", config.Backend)
+}
+
+// NewDigikalaFetcherFromConfig returns a DigikalaFetcher that mirrors products and records their prices into store
+// if enabled by config.
+func NewDigikalaFetcherFromConfig(
+	config Config, client *resty.Client, redisClient *redis.Client, store *storage.Storage,
+) DigikalaFetcher {
+	var mirrorStore *storage.Storage
+	if config.Mirror.Enabled {
+		mirrorStore = store
+	}
+	return NewDigikalaFetcher(
+		config.BaseUrl,
+		config.ApiBaseUrl,
+		client,
+		redisClient,
+		config.MaxRetry,
+		config.Upstream,
+		config.LocalCache,
+		config.Cache,
+		mirrorStore,
+		time.Duration(config.Mirror.MaxAge)*time.Second,
+		NewPriceRecorder(config.PriceHistory, store),
+	)
+}
@@ -0,0 +1,179 @@
+package productmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HttpJsonFetcher implements Fetcher interface{} for any catalog exposing products as JSON over HTTP. Fields are
+// extracted according to a FieldMapping.
+type HttpJsonFetcher struct {
+	asyncFetcher
+	baseUrl    string
+	productUrl string
+	mapping    FieldMapping
+	client     *resty.Client
+	upstream   upstreamGuard
+	prices     PriceRecorder
+}
+
+func NewHttpJsonFetcher(
+	baseUrl string, productUrl string, mapping FieldMapping, client *resty.Client, maxRetry int,
+	upstreamConfig UpstreamConfig, prices PriceRecorder,
+) HttpJsonFetcher {
+	f := HttpJsonFetcher{
+		baseUrl:    baseUrl,
+		productUrl: productUrl,
+		mapping:    mapping,
+		client:     client,
+		upstream:   newUpstreamGuard(upstreamConfig),
+		prices:     prices,
+	}
+	f.asyncFetcher = newAsyncFetcher(f.Fetch, maxRetry, 0, newBackoff(upstreamConfig))
+	return f
+}
+
+func (f HttpJsonFetcher) Fetch(ctx context.Context, product rank.Product) (*v1.Product, error) {
+	pid, err := strconv.Atoi(product.Id)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := f.fetchDoc(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	result := f.toProduct(pid, doc)
+	result.Score = product.Score
+	return result, nil
+}
+
+// FetchDetails returns the product with the brand, recommended retail price and gallery of the mapping. Variants,
+// seller count and specs are not supported by this backend.
+func (f HttpJsonFetcher) FetchDetails(ctx context.Context, pid int) (*v1.ProductDetails, error) {
+	doc, err := f.fetchDoc(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	product := f.toProduct(pid, doc)
+	rrpPrice := lookupInt(doc, f.mapping.RrpPrice)
+	return &v1.ProductDetails{
+		Product:         product,
+		Brand:           lookupString(doc, f.mapping.Brand),
+		Images:          f.images(doc),
+		RrpPrice:        rrpPrice,
+		DiscountPercent: discountPercent(product.Price, rrpPrice),
+	}, nil
+}
+
+// Refresh fetches the product to record its current price.
+func (f HttpJsonFetcher) Refresh(ctx context.Context, pid uint) error {
+	_, err := f.fetchDoc(ctx, int(pid))
+	return err
+}
+
+func (f HttpJsonFetcher) fetchDoc(ctx context.Context, pid int) (interface{}, error) {
+	url := f.productUrl
+	if strings.Contains(url, "{id}") {
+		url = strings.ReplaceAll(url, "{id}", strconv.Itoa(pid))
+	} else {
+		url = fmt.Sprintf("%s/%d/", strings.TrimSuffix(url, "/"), pid)
+	}
+	resp, err := f.upstream.get(ctx, f.client, url)
+	if err != nil {
+		return nil, classify(pid, err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, statusError(pid, resp.StatusCode(), resp.Status(), retryAfter(resp))
+	}
+	var doc interface{}
+	if err := json.Unmarshal(resp.Body(), &doc); err != nil {
+		return nil, newFetchError(pid, ErrDecode, err)
+	}
+	if f.mapping.Inactive != "" && lookupBool(doc, f.mapping.Inactive) {
+		return nil, newFetchError(pid, ErrInactive, nil)
+	}
+	f.prices.record(uint(pid), lookupInt(doc, f.mapping.Price))
+	return doc, nil
+}
+
+func (f HttpJsonFetcher) toProduct(pid int, doc interface{}) *v1.Product {
+	m := f.mapping
+	return &v1.Product{
+		Id:       int32(pid),
+		Title:    lookupString(doc, m.Title),
+		Url:      f.absoluteUrl(lookupString(doc, m.Url)),
+		Status:   lookupString(doc, m.Status),
+		ImageUrl: lookupString(doc, m.ImageUrl),
+		Rate: &v1.Rating{
+			Rate:  int32(lookupInt(doc, m.Rate)),
+			Count: int32(lookupInt(doc, m.RateCount)),
+		},
+		Categories: f.categories(doc),
+		Price:      lookupInt(doc, m.Price),
+	}
+}
+
+func (f HttpJsonFetcher) images(doc interface{}) []string {
+	images := make([]string, 0)
+	if f.mapping.Images == "" {
+		return images
+	}
+	val, ok := lookupPath(doc, f.mapping.Images)
+	if !ok {
+		return images
+	}
+	items, ok := val.([]interface{})
+	if !ok {
+		return images
+	}
+	for _, item := range items {
+		if url := lookupString(item, f.mapping.ImageItemUrl); url != "" {
+			images = append(images, url)
+		}
+	}
+	return images
+}
+
+func (f HttpJsonFetcher) categories(doc interface{}) []*v1.Category {
+	categories := make([]*v1.Category, 0)
+	if f.mapping.Categories == "" {
+		return categories
+	}
+	val, ok := lookupPath(doc, f.mapping.Categories)
+	if !ok {
+		return categories
+	}
+	items, ok := val.([]interface{})
+	if !ok {
+		return categories
+	}
+	for _, item := range items {
+		categories = append(categories, &v1.Category{
+			Title: lookupString(item, f.mapping.CategoryTitle),
+			Url:   f.absoluteUrl(lookupString(item, f.mapping.CategoryUrl)),
+		})
+	}
+	return categories
+}
+
+func (f HttpJsonFetcher) absoluteUrl(url string) string {
+	if url == "" || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return url
+	}
+	return fmt.Sprintf("%s%s", f.baseUrl, url)
+}
+
+// discountPercent returns the discount of price relative to the recommended retail price, rounded down.
+func discountPercent(price int64, rrpPrice int64) int32 {
+	if rrpPrice <= 0 || price >= rrpPrice {
+		return 0
+	}
+	return int32((rrpPrice - price) * 100 / rrpPrice)
+}
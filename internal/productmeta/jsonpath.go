@@ -0,0 +1,125 @@
+package productmeta
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookupPath resolves a dotted path such as "data.product.images.main.url[0]" against a decoded JSON document.
+// Array subscripts may be indices or slices ("breadcrumb[:-1]"); negative values count from the end of the array.
+// An empty path resolves to the document itself.
+func lookupPath(doc interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return doc, true
+	}
+	current := doc
+	for _, segment := range strings.Split(path, ".") {
+		name, subscripts := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, subscripts = segment[:i], segment[i+1:]
+		}
+		if name != "" {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = object[name]; !ok {
+				return nil, false
+			}
+		}
+		if subscripts == "" {
+			continue
+		}
+		for _, subscript := range strings.Split(subscripts, "[") {
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = applySubscript(array, strings.TrimSuffix(subscript, "]")); !ok {
+				return nil, false
+			}
+		}
+	}
+	return current, true
+}
+
+func applySubscript(array []interface{}, subscript string) (interface{}, bool) {
+	bounds := strings.SplitN(subscript, ":", 2)
+	if len(bounds) == 1 {
+		index, err := strconv.Atoi(subscript)
+		if err != nil {
+			return nil, false
+		}
+		if index < 0 {
+			index += len(array)
+		}
+		if index < 0 || index >= len(array) {
+			return nil, false
+		}
+		return array[index], true
+	}
+	start, end := 0, len(array)
+	for i, bound := range bounds {
+		if bound == "" {
+			continue
+		}
+		val, err := strconv.Atoi(bound)
+		if err != nil {
+			return nil, false
+		}
+		if val < 0 {
+			val += len(array)
+		}
+		if val < 0 {
+			val = 0
+		}
+		if val > len(array) {
+			val = len(array)
+		}
+		if i == 0 {
+			start = val
+		} else {
+			end = val
+		}
+	}
+	if start > end {
+		return []interface{}{}, true
+	}
+	return array[start:end], true
+}
+
+func lookupString(doc interface{}, path string) string {
+	val, ok := lookupPath(doc, path)
+	if !ok || val == nil {
+		return ""
+	}
+	if s, ok := val.(string); ok {
+		return s
+	}
+	return fmt.Sprint(val)
+}
+
+func lookupInt(doc interface{}, path string) int64 {
+	val, ok := lookupPath(doc, path)
+	if !ok {
+		return 0
+	}
+	switch v := val.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func lookupBool(doc interface{}, path string) bool {
+	val, ok := lookupPath(doc, path)
+	if !ok {
+		return false
+	}
+	b, _ := val.(bool)
+	return b
+}
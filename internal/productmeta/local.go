@@ -0,0 +1,110 @@
+package productmeta
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+const sharedFetchTimeout = 10 * time.Second
+
+var (
+	localCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_local_cache_hits_total",
+		Help: "Number of product fetches served from the in-process cache.",
+	})
+	localCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_local_cache_misses_total",
+		Help: "Number of product fetches not found in the in-process cache.",
+	})
+	coalescedFetches = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_fetch_coalesced_total",
+		Help: "Number of product fetches that shared the result of a concurrent fetch of the same product.",
+	})
+)
+
+// LocalCacheConfig configures the in-process cache of products. Size is the maximum number of products kept and
+// TTL the number of seconds they are kept for; a zero Size disables the cache.
+type LocalCacheConfig struct {
+	Size int
+	TTL  int64 `mapstructure:"ttl" yaml:"ttl"`
+}
+
+type localEntry struct {
+	product   *storage.Product
+	expiresAt time.Time
+}
+
+// localFetcher sits in front of the shared caches. It keeps recently fetched products in a bounded LRU and
+// coalesces concurrent fetches of the same product into a single call.
+type localFetcher struct {
+	cache *lru.Cache[uint, localEntry]
+	ttl   time.Duration
+	group *singleflight.Group
+}
+
+func newLocalFetcher(config LocalCacheConfig) localFetcher {
+	f := localFetcher{
+		ttl:   time.Duration(config.TTL) * time.Second,
+		group: &singleflight.Group{},
+	}
+	if config.Size > 0 {
+		f.cache, _ = lru.New[uint, localEntry](config.Size)
+	}
+	return f
+}
+
+// get returns the product from the LRU or calls fetch, sharing the call with concurrent callers. fetch runs with
+// its own context so that a canceled caller does not fail the others.
+func (f localFetcher) get(
+	ctx context.Context, pid uint, fetch func(ctx context.Context, pid uint) (*storage.Product, error),
+) (*storage.Product, error) {
+	if f.cache != nil {
+		if entry, ok := f.cache.Get(pid); ok && time.Now().Before(entry.expiresAt) {
+			localCacheHits.Inc()
+			return entry.product, nil
+		}
+		localCacheMisses.Inc()
+	}
+
+	// res.Shared is true for the caller that ran fetch as well, so only count the callers that didn't.
+	leader := false
+	ch := f.group.DoChan(strconv.Itoa(int(pid)), func() (interface{}, error) {
+		leader = true
+		sharedCtx, cancel := context.WithTimeout(context.Background(), sharedFetchTimeout)
+		defer cancel()
+		p, err := fetch(sharedCtx, pid)
+		if err != nil {
+			return nil, err
+		}
+		if f.cache != nil {
+			f.cache.Add(pid, localEntry{product: p, expiresAt: time.Now().Add(f.ttl)})
+		}
+		return p, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if !leader {
+			coalescedFetches.Inc()
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*storage.Product), nil
+	}
+}
+
+// forget drops the product from the LRU.
+func (f localFetcher) forget(pid uint) {
+	if f.cache != nil {
+		f.cache.Remove(pid)
+	}
+}
@@ -0,0 +1,116 @@
+package productmeta
+
+import (
+	"context"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"strconv"
+)
+
+// PostgresFetcher implements Fetcher interface{} on top of the local product catalog, e.g. for offline demos.
+type PostgresFetcher struct {
+	asyncFetcher
+	storage *storage.Storage
+}
+
+// NewPostgresFetcher returns a PostgresFetcher that runs at most concurrencyFactor queries of an AsyncFetch at once.
+func NewPostgresFetcher(store *storage.Storage, concurrencyFactor int) PostgresFetcher {
+	f := PostgresFetcher{
+		storage: store,
+	}
+	f.asyncFetcher = newAsyncFetcher(f.Fetch, 0, concurrencyFactor, backoff{})
+	return f
+}
+
+func (f PostgresFetcher) Fetch(ctx context.Context, product rank.Product) (*v1.Product, error) {
+	pid, err := strconv.Atoi(product.Id)
+	if err != nil {
+		return nil, err
+	}
+	p, err := f.get(pid)
+	if err != nil {
+		return nil, err
+	}
+	result := FromStorageProduct(p)
+	result.Score = product.Score
+	return result, nil
+}
+
+func (f PostgresFetcher) FetchDetails(ctx context.Context, pid int) (*v1.ProductDetails, error) {
+	p, err := f.get(pid)
+	if err != nil {
+		return nil, err
+	}
+	return FromStorageProductDetails(p), nil
+}
+
+func (f PostgresFetcher) get(pid int) (*storage.Product, error) {
+	p, err := f.storage.GetProductByID(uint(pid))
+	if err != nil {
+		return nil, newFetchError(pid, ErrNotFound, err)
+	}
+	if p.IsInactive {
+		return nil, newFetchError(pid, ErrInactive, nil)
+	}
+	return p, nil
+}
+
+// FromStorageProduct converts a catalog entry to its api representation.
+func FromStorageProduct(p *storage.Product) *v1.Product {
+	categories := make([]*v1.Category, len(p.Categories))
+	for i, c := range p.Categories {
+		categories[i] = &v1.Category{
+			Title: c.Title,
+			Url:   c.Url,
+		}
+	}
+	return &v1.Product{
+		Id:       int32(p.ID),
+		Title:    p.Title,
+		Url:      p.Url,
+		Status:   p.Status,
+		ImageUrl: p.ImageUrl,
+		Rate: &v1.Rating{
+			Rate:  p.Rate,
+			Count: p.RateCount,
+		},
+		Categories: categories,
+		Price:      p.Price,
+	}
+}
+
+// FromStorageProductDetails converts a catalog entry to its detailed api representation.
+func FromStorageProductDetails(p *storage.Product) *v1.ProductDetails {
+	variants := make([]*v1.ProductVariant, len(p.Variants))
+	for i, v := range p.Variants {
+		variants[i] = &v1.ProductVariant{
+			Id:              v.ID,
+			Color:           v.Color,
+			ColorHex:        v.ColorHex,
+			Size:            v.Size,
+			Price:           v.Price,
+			RrpPrice:        v.RrpPrice,
+			DiscountPercent: v.DiscountPercent,
+			InStock:         v.InStock,
+			Stock:           v.Stock,
+		}
+	}
+	specs := make([]*v1.ProductSpec, len(p.Specs))
+	for i, spec := range p.Specs {
+		specs[i] = &v1.ProductSpec{
+			Title:  spec.Title,
+			Values: spec.Values,
+		}
+	}
+	return &v1.ProductDetails{
+		Product:         FromStorageProduct(p),
+		Brand:           p.Brand,
+		Images:          p.Images,
+		Variants:        variants,
+		RrpPrice:        p.RrpPrice,
+		DiscountPercent: p.DiscountPercent,
+		SellerCount:     p.SellerCount,
+		Specs:           specs,
+	}
+}
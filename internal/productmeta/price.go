@@ -0,0 +1,89 @@
+package productmeta
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// PriceHistoryConfig configures recording the prices of products fetched from the upstream catalog. An unchanged
+// price is recorded at most once every MinInterval seconds. Every SyncInterval seconds a background job refreshes all
+// favorited products, BatchSize at a time, so their history has no gaps.
+type PriceHistoryConfig struct {
+	Enabled      bool
+	MinInterval  int64 `mapstructure:"min_interval" yaml:"min_interval"`
+	SyncInterval int64 `mapstructure:"sync_interval" yaml:"sync_interval"`
+	BatchSize    int   `mapstructure:"batch_size" yaml:"batch_size"`
+}
+
+const (
+	priceFlushInterval = time.Second
+	priceBatchSize     = 100
+	priceBufferSize    = 1000
+)
+
+type priceRecord struct {
+	pid   uint
+	price int64
+}
+
+// PriceRecorder appends fetched prices to the price history. Prices are buffered and written in batches by a
+// background goroutine, so fetches don't wait for the database; they may be lost on shutdown or dropped when the
+// buffer is full. The zero value records nothing.
+type PriceRecorder struct {
+	store       *storage.Storage
+	minInterval time.Duration
+	records     chan priceRecord
+}
+
+// NewPriceRecorder returns a PriceRecorder writing to store if price history is enabled by config.
+func NewPriceRecorder(config PriceHistoryConfig, store *storage.Storage) PriceRecorder {
+	if !config.Enabled {
+		return PriceRecorder{}
+	}
+	r := PriceRecorder{
+		store:       store,
+		minInterval: time.Duration(config.MinInterval) * time.Second,
+		records:     make(chan priceRecord, priceBufferSize),
+	}
+	go r.run()
+	return r
+}
+
+// record queues the price of a product. Unavailable products, whose price is zero, are skipped.
+func (r PriceRecorder) record(pid uint, price int64) {
+	if r.records == nil || price <= 0 {
+		return
+	}
+	select {
+	case r.records <- priceRecord{pid: pid, price: price}:
+	default:
+		logrus.Warnf("price history buffer is full, dropping the price of product %d", pid)
+	}
+}
+
+// run writes the queued prices every priceFlushInterval, or as soon as priceBatchSize products are queued. Only
+// the latest price of a product in a batch is kept.
+func (r PriceRecorder) run() {
+	ticker := time.NewTicker(priceFlushInterval)
+	defer ticker.Stop()
+	batch := make(map[uint]int64)
+	for {
+		select {
+		case record := <-r.records:
+			batch[record.pid] = record.price
+			if len(batch) < priceBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		if err := r.store.RecordPrices(batch, r.minInterval); err != nil {
+			logrus.Errorf("failed to record prices of %d products. err: %s", len(batch), err)
+		}
+		batch = make(map[uint]int64)
+	}
+}
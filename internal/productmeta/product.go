@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
 	v1 "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"time"
 )
 
 type Breadcrumb struct {
@@ -15,32 +17,63 @@ type Breadcrumb struct {
 }
 
 type Variant struct {
+	Id     int64  `json:"id"`
+	Status string `json:"status"`
+	Seller struct {
+		Id int64 `json:"id"`
+	} `json:"seller"`
+	Color struct {
+		Title   string `json:"title"`
+		HexCode string `json:"hex_code"`
+	} `json:"color"`
+	Size struct {
+		Title string `json:"title"`
+	} `json:"size"`
 	Price struct {
-		SellingPrice int64 `json:"selling_price"`
+		SellingPrice    int64 `json:"selling_price"`
+		RrpPrice        int64 `json:"rrp_price"`
+		DiscountPercent int32 `json:"discount_percent"`
+		MarketableStock int32 `json:"marketable_stock"`
 	} `json:"price"`
 }
 
+// UnmarshalJSON accepts the empty array digikala returns instead of an object for products without a variant.
 func (v *Variant) UnmarshalJSON(data []byte) error {
 	if len(data) == 0 || string(data) == "null" || (data[0] == '[' && data[len(data)-1] == ']') {
 		return nil
 	}
 	if data[0] == '{' && data[len(data)-1] == '}' { // object?
-		type TempVariant struct {
-			Price struct {
-				SellingPrice int64 `json:"selling_price"`
-			} `json:"price"`
-		}
+		type TempVariant Variant
 		var tempVariant TempVariant
 		err := json.Unmarshal(data, &tempVariant)
 		if err != nil {
 			return err
 		}
-		v.Price.SellingPrice = tempVariant.Price.SellingPrice
+		*v = Variant(tempVariant)
 		return nil
 	}
 	return errors.New("invalid variant")
 }
 
+func (v Variant) ToStorageVariant() storage.ProductVariant {
+	return storage.ProductVariant{
+		ID:              v.Id,
+		Color:           v.Color.Title,
+		ColorHex:        v.Color.HexCode,
+		Size:            v.Size.Title,
+		Price:           v.Price.SellingPrice,
+		RrpPrice:        v.Price.RrpPrice,
+		DiscountPercent: v.Price.DiscountPercent,
+		InStock:         v.Status == "marketable" && v.Price.MarketableStock > 0,
+		Stock:           v.Price.MarketableStock,
+	}
+}
+
+type Attribute struct {
+	Title  string   `json:"title"`
+	Values []string `json:"values"`
+}
+
 type DigikalaProduct struct {
 	Data struct {
 		Product struct {
@@ -50,10 +83,16 @@ type DigikalaProduct struct {
 				Uri string `json:"uri"`
 			} `json:"url"`
 			Status string `json:"status"`
+			Brand  struct {
+				TitleFa string `json:"title_fa"`
+			} `json:"brand"`
 			Images struct {
 				Main struct {
 					Url []string `json:"url"`
 				}
+				List []struct {
+					Url []string `json:"url"`
+				} `json:"list"`
 			} `json:"images"`
 			Rating struct {
 				Rate  int32 `json:"rate"`
@@ -61,6 +100,10 @@ type DigikalaProduct struct {
 			} `json:"rating"`
 			Breadcrumb     []Breadcrumb `json:"breadcrumb"`
 			DefaultVariant Variant      `json:"default_variant"`
+			Variants       []Variant    `json:"variants"`
+			Review         struct {
+				Attributes []Attribute `json:"attributes"`
+			} `json:"review"`
 		}
 	}
 }
@@ -73,6 +116,9 @@ func (b Breadcrumb) ToCategory(baseUrl string) *v1.Category {
 }
 
 func ToCategories(baseUrl string, breadcrumb []Breadcrumb) []*v1.Category {
+	if len(breadcrumb) == 0 {
+		return []*v1.Category{}
+	}
 	categories := make([]*v1.Category, len(breadcrumb)-1)
 	for i, b := range breadcrumb {
 		if i == len(breadcrumb)-1 {
@@ -82,3 +128,59 @@ func ToCategories(baseUrl string, breadcrumb []Breadcrumb) []*v1.Category {
 	}
 	return categories
 }
+
+// ToStorageProduct converts the digikala response to a local catalog entry synced now.
+func (dp DigikalaProduct) ToStorageProduct(pid uint, baseUrl string) *storage.Product {
+	p := dp.Data.Product
+	imageUrl := ""
+	if len(p.Images.Main.Url) != 0 {
+		imageUrl = p.Images.Main.Url[0]
+	}
+	categories := make([]storage.ProductCategory, 0)
+	for _, c := range ToCategories(baseUrl, p.Breadcrumb) {
+		categories = append(categories, storage.ProductCategory{
+			Title: c.Title,
+			Url:   c.Url,
+		})
+	}
+	images := make([]string, 0)
+	for _, image := range p.Images.List {
+		if len(image.Url) != 0 {
+			images = append(images, image.Url[0])
+		}
+	}
+	variants := make([]storage.ProductVariant, len(p.Variants))
+	sellers := make(map[int64]struct{})
+	for i, v := range p.Variants {
+		variants[i] = v.ToStorageVariant()
+		sellers[v.Seller.Id] = struct{}{}
+	}
+	specs := make([]storage.ProductSpec, len(p.Review.Attributes))
+	for i, a := range p.Review.Attributes {
+		specs[i] = storage.ProductSpec{
+			Title:  a.Title,
+			Values: a.Values,
+		}
+	}
+	product := &storage.Product{
+		Title:           p.TitleFa,
+		Url:             fmt.Sprintf("%s%s", baseUrl, p.Url.Uri),
+		Status:          p.Status,
+		ImageUrl:        imageUrl,
+		Rate:            p.Rating.Rate,
+		RateCount:       p.Rating.Count,
+		Price:           p.DefaultVariant.Price.SellingPrice,
+		IsInactive:      p.IsInactive,
+		Categories:      categories,
+		Brand:           p.Brand.TitleFa,
+		Images:          images,
+		RrpPrice:        p.DefaultVariant.Price.RrpPrice,
+		DiscountPercent: p.DefaultVariant.Price.DiscountPercent,
+		SellerCount:     int32(len(sellers)),
+		Variants:        variants,
+		Specs:           specs,
+		SyncedAt:        time.Now(),
+	}
+	product.ID = pid
+	return product
+}
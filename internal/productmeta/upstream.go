@@ -0,0 +1,195 @@
+package productmeta
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+var (
+	upstreamConcurrencyLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "product_upstream_concurrency_limit",
+		Help: "Current adaptive limit of concurrent requests to the upstream catalog.",
+	})
+	upstreamThrottled = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_upstream_throttled_total",
+		Help: "Number of upstream catalog requests rejected with 429 Too Many Requests.",
+	})
+)
+
+// UpstreamConfig configures how the upstream catalog api is called. Requests are limited to Rate per second with
+// bursts of Burst, and their concurrency adapts between MinConcurrency and MaxConcurrency: it grows additively
+// while requests succeed and is halved, at most once per second, when they fail. Failed fetches are retried with
+// exponential backoff starting at BackoffBase milliseconds and capped at BackoffMax milliseconds, unless the
+// upstream asks for a longer wait with Retry-After.
+type UpstreamConfig struct {
+	Rate           float64
+	Burst          int
+	MinConcurrency int   `mapstructure:"min_concurrency" yaml:"min_concurrency"`
+	MaxConcurrency int   `mapstructure:"max_concurrency" yaml:"max_concurrency"`
+	BackoffBase    int64 `mapstructure:"backoff_base" yaml:"backoff_base"`
+	BackoffMax     int64 `mapstructure:"backoff_max" yaml:"backoff_max"`
+}
+
+// backoff computes the delay before a retry using exponential backoff with full jitter.
+type backoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+func newBackoff(config UpstreamConfig) backoff {
+	return backoff{
+		base: time.Duration(config.BackoffBase) * time.Millisecond,
+		max:  time.Duration(config.BackoffMax) * time.Millisecond,
+	}
+}
+
+// delay returns the delay before the given retry, at least retryAfter as asked by the upstream but never more than
+// the maximum backoff, so one response can't park a fetch until its context ends.
+func (b backoff) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > b.max {
+		retryAfter = b.max
+	}
+	ceiling := float64(b.base) * math.Pow(2, float64(attempt))
+	if ceiling > float64(b.max) {
+		ceiling = float64(b.max)
+	}
+	d := time.Duration(rand.Int63n(int64(ceiling) + 1))
+	if retryAfter > d {
+		return retryAfter
+	}
+	return d
+}
+
+// upstreamGuard protects the upstream catalog api with a token bucket shared by all fetches and an AIMD
+// concurrency limit.
+type upstreamGuard struct {
+	limiter *rate.Limiter
+	aimd    *aimdLimiter
+}
+
+var (
+	upstreamGuardsMu sync.Mutex
+	upstreamGuards   = make(map[UpstreamConfig]upstreamGuard)
+)
+
+// newUpstreamGuard returns the upstreamGuard of config. Fetchers created with the same config, e.g. the one of the
+// server and the one of the jobs, share it, so the limits hold for the whole process.
+func newUpstreamGuard(config UpstreamConfig) upstreamGuard {
+	upstreamGuardsMu.Lock()
+	defer upstreamGuardsMu.Unlock()
+	if g, ok := upstreamGuards[config]; ok {
+		return g
+	}
+	g := upstreamGuard{
+		limiter: rate.NewLimiter(rate.Limit(config.Rate), config.Burst),
+		aimd:    newAIMDLimiter(config.MinConcurrency, config.MaxConcurrency),
+	}
+	upstreamGuards[config] = g
+	return g
+}
+
+// get issues a GET request to url once both limits allow it.
+func (g upstreamGuard) get(ctx context.Context, client *resty.Client, url string) (*resty.Response, error) {
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if err := g.aimd.acquire(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := client.R().SetContext(ctx).Get(url)
+	failed := err != nil && ctx.Err() == nil
+	if err == nil {
+		if resp.StatusCode() == http.StatusTooManyRequests {
+			upstreamThrottled.Inc()
+		}
+		failed = resp.StatusCode() == http.StatusTooManyRequests || resp.StatusCode() >= 500
+	}
+	g.aimd.release(failed)
+	return resp, err
+}
+
+// retryAfter parses the Retry-After header of a response, either as seconds or as an http date.
+func retryAfter(resp *resty.Response) time.Duration {
+	header := resp.Header().Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+const aimdDecreaseCooldown = time.Second
+
+// aimdLimiter bounds the number of in-flight requests by a limit that is increased by one per limit successful
+// requests and halved on failures.
+type aimdLimiter struct {
+	mu           sync.Mutex
+	limit        float64
+	min          float64
+	max          float64
+	inflight     int
+	lastDecrease time.Time
+	// released is closed and replaced whenever a request finishes, waking up the waiting ones.
+	released chan struct{}
+}
+
+func newAIMDLimiter(min int, max int) *aimdLimiter {
+	l := &aimdLimiter{
+		limit:    float64(max),
+		min:      float64(min),
+		max:      float64(max),
+		released: make(chan struct{}),
+	}
+	upstreamConcurrencyLimit.Set(l.limit)
+	return l
+}
+
+func (l *aimdLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if float64(l.inflight) < math.Floor(l.limit) {
+			l.inflight++
+			l.mu.Unlock()
+			return nil
+		}
+		released := l.released
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+func (l *aimdLimiter) release(failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	if failed {
+		if time.Since(l.lastDecrease) > aimdDecreaseCooldown {
+			l.limit = math.Max(l.min, l.limit/2)
+			l.lastDecrease = time.Now()
+		}
+	} else {
+		l.limit = math.Min(l.max, l.limit+1/l.limit)
+	}
+	upstreamConcurrencyLimit.Set(l.limit)
+	close(l.released)
+	l.released = make(chan struct{})
+}
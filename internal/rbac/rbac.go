@@ -0,0 +1,67 @@
+package rbac
+
+// Roles of users. Every user has exactly one role.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+	// RoleGuest is the role of guests, who use the app without an account until they register or log in. It grants
+	// nothing and can't be assigned.
+	RoleGuest = "guest"
+)
+
+// Permissions granted by roles.
+const (
+	PermUsersRead      = "users:read"
+	PermUsersWrite     = "users:write"
+	PermSessionsRevoke = "sessions:revoke"
+	PermUsageRead      = "usage:read"
+	PermApiKeysWrite   = "api_keys:write"
+)
+
+// Roles maps every role to its permissions.
+var Roles = map[string][]string{
+	RoleUser:    {},
+	RoleSupport: {PermUsersRead, PermSessionsRevoke, PermUsageRead},
+	RoleAdmin:   {PermUsersRead, PermUsersWrite, PermSessionsRevoke, PermUsageRead, PermApiKeysWrite},
+}
+
+// IsRole reports whether role exists.
+func IsRole(role string) bool {
+	_, ok := Roles[role]
+	return ok
+}
+
+// Has reports whether role grants permission.
+func Has(role string, permission string) bool {
+	for _, p := range Roles[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule is the requirement to call an RPC.
+type Rule struct {
+	// Authenticated requires a logged in user.
+	Authenticated bool
+	// Permission, if set, requires a logged in user whose role grants it.
+	Permission string
+	// Guests lets guests call an RPC that requires a logged in user.
+	Guests bool
+}
+
+var (
+	// Public lets anyone call an RPC.
+	Public = Rule{}
+	// Authenticated lets logged in users call an RPC.
+	Authenticated = Rule{Authenticated: true}
+	// Guests lets logged in users and guests call an RPC.
+	Guests = Rule{Authenticated: true, Guests: true}
+)
+
+// Requires lets users whose role grants permission call an RPC.
+func Requires(permission string) Rule {
+	return Rule{Authenticated: true, Permission: permission}
+}
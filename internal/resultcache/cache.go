@@ -0,0 +1,35 @@
+package resultcache
+
+import (
+	"context"
+
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+)
+
+// Key identifies a ranked search result. Vector is the query embedding; it is quantized before hashing so that
+// near-identical queries share the same entry.
+type Key struct {
+	Vector  []float32
+	TopK    int
+	Ranker  string
+	Filters map[string]string
+}
+
+// Cache stores fully ranked product lists so that repeated queries can skip the vector search.
+type Cache interface {
+	Get(ctx context.Context, key Key) ([]rank.Product, bool)
+	Set(ctx context.Context, key Key, products []rank.Product)
+}
+
+// NoopCache implements Cache interface{} without storing anything
+type NoopCache struct {
+}
+
+// Get implements Cache interface{}
+func (NoopCache) Get(ctx context.Context, key Key) ([]rank.Product, bool) {
+	return nil, false
+}
+
+// Set implements Cache interface{}
+func (NoopCache) Set(ctx context.Context, key Key, products []rank.Product) {
+}
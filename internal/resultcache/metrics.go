@@ -0,0 +1,17 @@
+package resultcache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "search_result_cache_hits_total",
+		Help: "Number of searches served from the ranked result cache.",
+	})
+	cacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "search_result_cache_misses_total",
+		Help: "Number of searches that were not found in the ranked result cache.",
+	})
+)
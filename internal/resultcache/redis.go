@@ -0,0 +1,126 @@
+package resultcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+)
+
+// RedisCache implements Cache interface{}
+// Entries are namespaced by a collection version stored in redis. The indexing pipeline invalidates every cached
+// result of a collection by incrementing the version key (see VersionKey).
+type RedisCache struct {
+	client           *redis.Client
+	collectionName   string
+	ttl              time.Duration
+	quantizationStep float64
+}
+
+// NewRedisCache returns a new RedisCache
+func NewRedisCache(
+	client *redis.Client,
+	collectionName string,
+	ttl time.Duration,
+	quantizationStep float64,
+) *RedisCache {
+	return &RedisCache{
+		client:           client,
+		collectionName:   collectionName,
+		ttl:              ttl,
+		quantizationStep: quantizationStep,
+	}
+}
+
+// VersionKey returns the redis key holding the current version of the given collection.
+func VersionKey(collectionName string) string {
+	return fmt.Sprintf("search:version:%s", collectionName)
+}
+
+// BumpVersion invalidates all cached results of the collection and returns the new version.
+func (c *RedisCache) BumpVersion(ctx context.Context) (int64, error) {
+	return c.client.Incr(ctx, VersionKey(c.collectionName)).Result()
+}
+
+// Get implements Cache interface{}
+func (c *RedisCache) Get(ctx context.Context, key Key) ([]rank.Product, bool) {
+	redisKey, err := c.redisKey(ctx, key)
+	if err != nil {
+		logrus.Error("failed to build search cache key: ", err)
+		cacheMisses.Inc()
+		return nil, false
+	}
+	val, err := c.client.Get(ctx, redisKey).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logrus.Error("failed to read search result from redis: ", err)
+		}
+		cacheMisses.Inc()
+		return nil, false
+	}
+	var products []rank.Product
+	if err := json.Unmarshal(val, &products); err != nil {
+		logrus.Error("failed to decode cached search result: ", err)
+		cacheMisses.Inc()
+		return nil, false
+	}
+	cacheHits.Inc()
+	return products, true
+}
+
+// Set implements Cache interface{}
+func (c *RedisCache) Set(ctx context.Context, key Key, products []rank.Product) {
+	redisKey, err := c.redisKey(ctx, key)
+	if err != nil {
+		logrus.Error("failed to build search cache key: ", err)
+		return
+	}
+	val, err := json.Marshal(products)
+	if err != nil {
+		logrus.Error("failed to encode search result: ", err)
+		return
+	}
+	if err := c.client.Set(ctx, redisKey, val, c.ttl).Err(); err != nil {
+		logrus.Error("failed to write search result to redis: ", err)
+	}
+}
+
+func (c *RedisCache) redisKey(ctx context.Context, key Key) (string, error) {
+	version, err := c.client.Get(ctx, VersionKey(c.collectionName)).Int64()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"search:result:%s:%d:%s:%d:%s",
+		c.collectionName, version, key.Ranker, key.TopK, c.hash(key),
+	), nil
+}
+
+// hash digests the quantized query vector together with the filters.
+func (c *RedisCache) hash(key Key) string {
+	h := sha256.New()
+	buf := make([]byte, 8)
+	for _, v := range key.Vector {
+		q := int64(math.Round(float64(v) / c.quantizationStep))
+		binary.LittleEndian.PutUint64(buf, uint64(q))
+		h.Write(buf)
+	}
+	names := make([]string, 0, len(key.Filters))
+	for name := range key.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "|%s=%s", name, key.Filters[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -41,3 +41,7 @@ func (m *MinioClient) Upload(ctx context.Context, bucket, path string, file io.R
 func (m *MinioClient) Download(ctx context.Context, bucket, path string) (io.ReadCloser, error) {
 	return m.minioClient.GetObject(ctx, bucket, path, minio.GetObjectOptions{})
 }
+
+func (m *MinioClient) Remove(ctx context.Context, bucket, path string) error {
+	return m.minioClient.RemoveObject(ctx, bucket, path, minio.RemoveObjectOptions{})
+}
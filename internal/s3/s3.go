@@ -5,7 +5,11 @@ import (
 	"io"
 )
 
+// HistoryImagesBucket is the bucket the query images of search histories are stored in.
+const HistoryImagesBucket = "history-images"
+
 type Client interface {
 	Upload(ctx context.Context, bucket, path string, file io.Reader, size int64) error
 	Download(ctx context.Context, bucket, path string) (io.ReadCloser, error)
+	Remove(ctx context.Context, bucket, path string) error
 }
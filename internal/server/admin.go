@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/apikey"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rbac"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	defaultUsersLimit = 20
+	defaultUsageDays  = 30
+)
+
+// AdminServiceServer serves the operational apis for staff. Who may call what is declared in the rpc policy.
+type AdminServiceServer struct {
+	pb.UnimplementedAdminServiceServer
+	Storage *storage.Storage
+	ApiKeys *apikey.Manager
+}
+
+func NewAdminServiceServer(storage *storage.Storage, apiKeys *apikey.Manager) *AdminServiceServer {
+	return &AdminServiceServer{
+		Storage: storage,
+		ApiKeys: apiKeys,
+	}
+}
+
+func (s *AdminServiceServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultUsersLimit
+	}
+	users, total, err := s.Storage.SearchUsers(req.Query, req.Role, int(req.Offset), limit)
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	activity, err := s.Storage.GetUserActivity(ids)
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	respUsers := make([]*pb.AdminUser, len(users))
+	for i := range users {
+		respUsers[i] = toAdminUser(&users[i], activity[users[i].ID])
+	}
+	return &pb.ListUsersResponse{Users: respUsers, Total: int32(total)}, nil
+}
+
+func (s *AdminServiceServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.AdminUser, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user, err := s.Storage.GetUserByID(uint(req.UserId))
+	if err != nil {
+		return nil, errors.NotFound
+	}
+	activity, err := s.Storage.GetUserActivity([]uint{user.ID})
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	return toAdminUser(user, activity[user.ID]), nil
+}
+
+func (s *AdminServiceServer) SetUserRole(ctx context.Context, req *pb.SetUserRoleRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !rbac.IsRole(req.Role) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown role %q", req.Role)
+	}
+	if _, err := s.Storage.GetUserByID(uint(req.UserId)); err != nil {
+		return nil, errors.NotFound
+	}
+	if err := s.Storage.SetUserRole(uint(req.UserId), req.Role); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	logrus.WithFields(logrus.Fields{
+		"by":      contextUserID(ctx),
+		"user_id": req.UserId,
+		"role":    req.Role,
+	}).Info("user role changed")
+	return &emptypb.Empty{}, nil
+}
+
+// DisableUser disables an account and revokes all of its sessions.
+func (s *AdminServiceServer) DisableUser(ctx context.Context, req *pb.DisableUserRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if uint(req.UserId) == contextUserID(ctx) {
+		return nil, status.Error(codes.FailedPrecondition, "you can't disable your own account")
+	}
+	if _, err := s.Storage.GetUserByID(uint(req.UserId)); err != nil {
+		return nil, errors.NotFound
+	}
+	if err := s.Storage.SetUserDisabled(uint(req.UserId), true, req.Reason); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	if err := s.Storage.RevokeAllSessions(uint(req.UserId)); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	logrus.WithFields(logrus.Fields{
+		"by":      contextUserID(ctx),
+		"user_id": req.UserId,
+		"reason":  req.Reason,
+	}).Info("user disabled")
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AdminServiceServer) EnableUser(ctx context.Context, req *pb.EnableUserRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := s.Storage.GetUserByID(uint(req.UserId)); err != nil {
+		return nil, errors.NotFound
+	}
+	if err := s.Storage.SetUserDisabled(uint(req.UserId), false, ""); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	logrus.WithFields(logrus.Fields{
+		"by":      contextUserID(ctx),
+		"user_id": req.UserId,
+	}).Info("user enabled")
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AdminServiceServer) RevokeUserSessions(
+	ctx context.Context, req *pb.RevokeUserSessionsRequest,
+) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.Storage.RevokeAllSessions(uint(req.UserId)); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	logrus.WithFields(logrus.Fields{
+		"by":      contextUserID(ctx),
+		"user_id": req.UserId,
+	}).Info("user sessions revoked")
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AdminServiceServer) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	days := int(req.Days)
+	if days == 0 {
+		days = defaultUsageDays
+	}
+	usage, err := s.Storage.GetDailyUsage(time.Now().UTC().AddDate(0, 0, 1-days))
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	totalUsers, err := s.Storage.CountUsers()
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	activeSessions, err := s.Storage.CountActiveSessions()
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	respDays := make([]*pb.DailyUsage, len(usage))
+	for i, day := range usage {
+		respDays[i] = &pb.DailyUsage{
+			Day:         day.Day.Unix(),
+			NewUsers:    int32(day.NewUsers),
+			Logins:      int32(day.Logins),
+			Searches:    int32(day.Searches),
+			ActiveUsers: int32(day.ActiveUsers),
+		}
+	}
+	return &pb.GetUsageResponse{
+		Days:           respDays,
+		TotalUsers:     int32(totalUsers),
+		ActiveSessions: int32(activeSessions),
+	}, nil
+}
+
+// CreateApiKey issues a key for a partner. Keys can only be scoped to public rpcs.
+func (s *AdminServiceServer) CreateApiKey(
+	ctx context.Context, req *pb.CreateApiKeyRequest,
+) (*pb.CreateApiKeyResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, scope := range req.Scopes {
+		if !apiKeyScopeAllowed(scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "api keys can't be scoped to %q", scope)
+		}
+	}
+	key := &storage.ApiKey{
+		Name:        req.Name,
+		Scopes:      req.Scopes,
+		RateLimit:   int(req.RateLimit),
+		CreatedByID: contextUserID(ctx),
+	}
+	if req.ExpiresAt != 0 {
+		expiresAt := time.Unix(req.ExpiresAt, 0)
+		if expiresAt.Before(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expires_at is in the past")
+		}
+		key.ExpiresAt = &expiresAt
+	}
+	plain, err := s.ApiKeys.Create(key)
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	logrus.WithFields(logrus.Fields{
+		"by":         contextUserID(ctx),
+		"api_key_id": key.ID,
+		"scopes":     key.Scopes,
+	}).Info("api key created")
+	return &pb.CreateApiKeyResponse{ApiKey: toApiKey(key), Key: plain}, nil
+}
+
+func (s *AdminServiceServer) ListApiKeys(
+	ctx context.Context, req *pb.ListApiKeysRequest,
+) (*pb.ListApiKeysResponse, error) {
+	keys, err := s.Storage.GetApiKeys()
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	respKeys := make([]*pb.ApiKey, len(keys))
+	for i := range keys {
+		respKeys[i] = toApiKey(&keys[i])
+	}
+	return &pb.ListApiKeysResponse{ApiKeys: respKeys}, nil
+}
+
+func (s *AdminServiceServer) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	key, err := s.Storage.GetApiKeyByID(uint(req.ApiKeyId))
+	if err != nil {
+		return nil, errors.NotFound
+	}
+	if err := s.Storage.RevokeApiKey(key.ID); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	s.ApiKeys.Forget(key)
+	logrus.WithFields(logrus.Fields{
+		"by":         contextUserID(ctx),
+		"api_key_id": req.ApiKeyId,
+	}).Info("api key revoked")
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AdminServiceServer) GetApiKeyUsage(
+	ctx context.Context, req *pb.GetApiKeyUsageRequest,
+) (*pb.GetApiKeyUsageResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := s.Storage.GetApiKeyByID(uint(req.ApiKeyId)); err != nil {
+		return nil, errors.NotFound
+	}
+	days := int(req.Days)
+	if days == 0 {
+		days = defaultUsageDays
+	}
+	usage, err := s.Storage.GetApiKeyUsage(uint(req.ApiKeyId), time.Now().UTC().AddDate(0, 0, 1-days))
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	var total int64
+	respUsage := make([]*pb.ApiKeyUsage, len(usage))
+	for i, u := range usage {
+		respUsage[i] = &pb.ApiKeyUsage{
+			Day:    u.Day.Unix(),
+			Method: u.Method,
+			Count:  u.Count,
+		}
+		total += u.Count
+	}
+	return &pb.GetApiKeyUsageResponse{Usage: respUsage, Total: total}, nil
+}
+
+func toApiKey(key *storage.ApiKey) *pb.ApiKey {
+	result := &pb.ApiKey{
+		Id:        int32(key.ID),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		RateLimit: int32(key.RateLimit),
+		CreatedAt: key.CreatedAt.Unix(),
+		Revoked:   key.RevokedAt != nil,
+	}
+	if key.ExpiresAt != nil {
+		result.ExpiresAt = key.ExpiresAt.Unix()
+	}
+	if key.LastUsedAt != nil {
+		result.LastUsedAt = key.LastUsedAt.Unix()
+	}
+	return result
+}
+
+func toAdminUser(user *storage.UserAccount, activity *storage.UserActivity) *pb.AdminUser {
+	result := &pb.AdminUser{
+		Id:             int32(user.ID),
+		Email:          user.Email,
+		PhoneNumber:    user.PhoneNumber,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Role:           user.Role,
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		PhoneVerified:  user.PhoneVerifiedAt != nil,
+		Disabled:       user.DisabledAt != nil,
+		DisabledReason: user.DisabledReason,
+		CreatedAt:      user.CreatedAt.Unix(),
+	}
+	if activity != nil {
+		if activity.LastSeenAt != nil {
+			result.LastSeenAt = activity.LastSeenAt.Unix()
+		}
+		result.SearchCount = int32(activity.SearchCount)
+		result.ActiveSessions = int32(activity.ActiveSessions)
+	}
+	return result
+}
+
+// contextUserID returns the id of the calling user, or zero if there is none.
+func contextUserID(ctx context.Context) uint {
+	if user := GetContextUser(ctx); user != nil {
+		return user.ID
+	}
+	return 0
+}
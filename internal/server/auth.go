@@ -2,25 +2,59 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/captcha"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/loginguard"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/oidc"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/otp"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rbac"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/s3"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/twofactor"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/verification"
 	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// passwordResetTimeout bounds sending a password reset email, which happens after the request has returned.
+const passwordResetTimeout = 30 * time.Second
+
 type AuthServiceServer struct {
 	pb.UnimplementedAuthServiceServer
 	AuthTokenExpire    int64
 	RefreshTokenExpire int64
 	TokenManager       token.Manager
 	Storage            *storage.Storage
+	Mailer             mail.Mailer
+	// PasswordResetExpire is how long a password reset token is valid, in seconds. PasswordResetUrl is the page
+	// the reset email links to, with a {token} placeholder.
+	PasswordResetExpire int64
+	PasswordResetUrl    string
+	Verifier            *verification.Verifier
+	OTP                 *otp.Manager
+	OIDC                *oidc.Manager
+	Guard               *loginguard.Guard
+	Captcha             captcha.Verifier
+	S3Client            s3.Client
+	TwoFactor           *twofactor.Manager
 }
 
 func NewAuthServiceServer(
@@ -28,26 +62,85 @@ func NewAuthServiceServer(
 	storage *storage.Storage,
 	authTokenExpire int64,
 	refreshTokenExpire int64,
+	mailer mail.Mailer,
+	passwordResetExpire int64,
+	passwordResetUrl string,
+	verifier *verification.Verifier,
+	otpManager *otp.Manager,
+	oidcManager *oidc.Manager,
+	guard *loginguard.Guard,
+	captchaVerifier captcha.Verifier,
+	s3Client s3.Client,
+	twoFactorManager *twofactor.Manager,
 ) *AuthServiceServer {
 	return &AuthServiceServer{
-		TokenManager:       tokenManager,
-		Storage:            storage,
-		AuthTokenExpire:    authTokenExpire,
-		RefreshTokenExpire: refreshTokenExpire,
+		TokenManager:        tokenManager,
+		Storage:             storage,
+		AuthTokenExpire:     authTokenExpire,
+		RefreshTokenExpire:  refreshTokenExpire,
+		Mailer:              mailer,
+		PasswordResetExpire: passwordResetExpire,
+		PasswordResetUrl:    passwordResetUrl,
+		Verifier:            verifier,
+		OTP:                 otpManager,
+		OIDC:                oidcManager,
+		Guard:               guard,
+		Captcha:             captchaVerifier,
+		S3Client:            s3Client,
+		TwoFactor:           twoFactorManager,
 	}
 }
 
+// Login checks the email and password of a user. Failed logins are counted per account and ip, which get locked
+// out after too many and may need a captcha before that. Users with two factor authentication get a challenge to
+// finish the login with VerifyTwoFactor.
 func (s *AuthServiceServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	ip := getClientIP(ctx)
+	state := s.Guard.Attempt(ctx, req.Email, ip)
+	if state.RetryAfter > 0 {
+		return nil, retryError("too many failed logins, try again later", state.RetryAfter)
+	}
+	if state.CaptchaRequired && s.Captcha.Enabled() {
+		ok, err := s.Captcha.Verify(ctx, req.CaptchaToken, ip)
+		if err != nil {
+			logrus.Error("failed to verify captcha: ", err)
+		}
+		if !ok {
+			s.Guard.Cancel(ctx, req.Email, ip)
+			return nil, captchaRequiredError()
+		}
+	}
+
 	user, err := s.Storage.GetUserByEmail(req.Email)
-	if err != nil {
-		return nil, status.Error(codes.NotFound, "user not found")
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
+		s.loginFailed(ctx, req.Email, ip, user)
 		return nil, status.Error(codes.NotFound, "user not found")
 	}
-	authToken, refreshToken, err := s.generateTokens(user)
+	if user.DisabledAt != nil {
+		s.Guard.Succeed(ctx, req.Email, ip)
+		return nil, errors.AccountDisabled
+	}
+	// failures are forgotten once the code is entered too, so codes can't be guessed after every right password
+	if s.TwoFactor.Enabled(user.ID) {
+		s.Guard.ReleaseIP(ctx, ip)
+	} else {
+		s.Guard.Succeed(ctx, req.Email, ip)
+	}
+	return s.login(ctx, user, req.DeviceName)
+}
+
+// login returns the tokens of a new session of a user who proved who they are, or a challenge for the second
+// factor if they have two factor authentication. Every login of an existing user goes through it.
+func (s *AuthServiceServer) login(ctx context.Context, user *storage.UserAccount, deviceName string) (*pb.LoginResponse, error) {
+	if s.TwoFactor.Enabled(user.ID) {
+		return s.twoFactorChallenge(user, deviceName)
+	}
+	authToken, refreshToken, err := s.generateTokens(ctx, user, deviceName)
 	if err != nil {
+		logrus.Errorln(err)
 		return nil, status.Error(codes.Internal, "could not generate tokens")
 	}
 	return &pb.LoginResponse{
@@ -56,11 +149,113 @@ func (s *AuthServiceServer) Login(ctx context.Context, req *pb.LoginRequest) (*p
 	}, nil
 }
 
+// loginFailed ends a failed login attempt and audits the lockouts it causes. user is nil if no user has the email.
+func (s *AuthServiceServer) loginFailed(ctx context.Context, email string, ip string, user *storage.UserAccount) {
+	for _, lockout := range s.Guard.Fail(ctx, email, ip) {
+		entry := &storage.AuditEntry{
+			Action:  "login_lockout",
+			Subject: email,
+			IP:      ip,
+			Details: map[string]interface{}{
+				"scope":    lockout.Scope,
+				"failures": lockout.Failures,
+				"duration": lockout.Duration.Seconds(),
+			},
+		}
+		if lockout.Scope == "ip" {
+			entry.Subject = ip
+		}
+		if user != nil && lockout.Scope == "account" {
+			entry.UserID = user.ID
+		}
+		logrus.WithFields(logrus.Fields{
+			"subject":  entry.Subject,
+			"ip":       ip,
+			"duration": lockout.Duration,
+		}).Warn("login locked out")
+		if err := s.Storage.CreateAuditEntry(entry); err != nil {
+			logrus.Errorln(err)
+		}
+	}
+}
+
+// errWrongPassword is the error of a wrong password of a logged in user.
+var errWrongPassword = status.Error(codes.PermissionDenied, "wrong password")
+
+// checkPassword checks the password of a logged in user like a login, see guardAttempt.
+func (s *AuthServiceServer) checkPassword(ctx context.Context, user *storage.UserAccount, password string) error {
+	return s.guardAttempt(ctx, user, func() error {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return errWrongPassword
+		}
+		return nil
+	})
+}
+
+// guardAttempt runs check, which checks a password or code the user entered, as a login attempt of the user: wrong
+// ones count as failed logins, so they can't be guessed through one endpoint once another locked them out.
+func (s *AuthServiceServer) guardAttempt(ctx context.Context, user *storage.UserAccount, check func() error) error {
+	ip, account := getClientIP(ctx), guardAccount(user)
+	if state := s.Guard.Attempt(ctx, account, ip); state.RetryAfter > 0 {
+		return retryError("too many failed logins, try again later", state.RetryAfter)
+	}
+	err := check()
+	switch err {
+	case nil:
+		s.Guard.Succeed(ctx, account, ip)
+		return nil
+	case twofactor.ErrWrongCode, errWrongPassword:
+		s.loginFailed(ctx, account, ip, user)
+	default:
+		s.Guard.Cancel(ctx, account, ip)
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return twoFactorError(err)
+}
+
+// guardAccount is the account the failed codes of a user are counted for. It's the email of password logins, so
+// both share their failures, and the id of users without an email, e.g. of phone logins.
+func guardAccount(user *storage.UserAccount) string {
+	if user.Email != "" {
+		return user.Email
+	}
+	return "user:" + strconv.Itoa(int(user.ID))
+}
+
+// retryError returns a ResourceExhausted status telling the client when to retry.
+func retryError(msg string, retryAfter time.Duration) error {
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter.Round(time.Second)),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
+
+// captchaRequiredError tells the client to solve a captcha and log in again with its token.
+func captchaRequiredError() error {
+	msg := "captcha required"
+	st, err := status.New(codes.FailedPrecondition, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason: "CAPTCHA_REQUIRED",
+		Domain: "digivision",
+	})
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+	return st.Err()
+}
+
 func (s *AuthServiceServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
 	err := req.Validate()
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if retryAfter := s.Guard.AllowRegister(ctx, getClientIP(ctx)); retryAfter > 0 {
+		return nil, retryError("too many registrations, try again later", retryAfter)
+	}
 	_, err = s.Storage.GetUserByEmail(req.Email)
 	if err == nil {
 		return nil, status.Error(codes.AlreadyExists, "email already exists")
@@ -82,36 +277,271 @@ func (s *AuthServiceServer) Register(ctx context.Context, req *pb.RegisterReques
 		LastName:     req.LastName,
 		PasswordHash: string(hash),
 	}
-	err = s.Storage.CreateUser(&user)
+	err = s.createUser(&user)
 	if err != nil {
 		logrus.Errorln(err)
 		return nil, status.Error(codes.Internal, "could not create user")
 	}
-	err = s.Storage.CreateFavoriteList(&storage.FavoriteList{UserID: user.ID, Name: "favorites"})
+	authToken, refreshToken, err := s.generateTokens(ctx, &user, req.DeviceName)
 	if err != nil {
 		logrus.Errorln(err)
+		return nil, status.Error(codes.Internal, "could not generate tokens")
+	}
+	return &pb.RegisterResponse{
+		AuthToken:    authToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// CreateGuest starts a session of a new guest. Guests are limited per ip like registrations.
+func (s *AuthServiceServer) CreateGuest(ctx context.Context, req *pb.CreateGuestRequest) (*pb.CreateGuestResponse, error) {
+	if user := GetContextUser(ctx); user != nil && !user.IsGuest() {
+		return nil, status.Error(codes.FailedPrecondition, "already logged in")
+	}
+	if retryAfter := s.Guard.AllowGuest(ctx, getClientIP(ctx)); retryAfter > 0 {
+		return nil, retryError("too many guests, try again later", retryAfter)
+	}
+	user := storage.UserAccount{Role: rbac.RoleGuest}
+	if err := s.createUser(&user); err != nil {
+		logrus.Errorln(err)
+		return nil, status.Error(codes.Internal, "could not create guest")
 	}
-	authToken, refreshToken, err := s.generateTokens(&user)
+	authToken, refreshToken, err := s.generateTokens(ctx, &user, req.DeviceName)
 	if err != nil {
 		logrus.Errorln(err)
 		return nil, status.Error(codes.Internal, "could not generate tokens")
 	}
-	return &pb.RegisterResponse{
+	return &pb.CreateGuestResponse{
 		AuthToken:    authToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
 
+// createUser stores a new user along with their default favorite list.
+func (s *AuthServiceServer) createUser(user *storage.UserAccount) error {
+	err := s.Storage.CreateUser(user)
+	if err != nil {
+		return err
+	}
+	err = s.Storage.CreateFavoriteList(&storage.FavoriteList{UserID: user.ID, Name: "favorites"})
+	if err != nil {
+		logrus.Errorln(err)
+	}
+	return nil
+}
+
+// RequestOTP sends a login code to a phone number, see VerifyOTP.
+func (s *AuthServiceServer) RequestOTP(ctx context.Context, req *pb.RequestOTPRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if retryAfter := s.Guard.AllowOTP(ctx, getClientIP(ctx)); retryAfter > 0 {
+		return nil, retryError("too many codes requested, try again later", retryAfter)
+	}
+	if err := s.OTP.Request(ctx, req.PhoneNumber); err != nil {
+		return nil, otpError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// VerifyOTP logs in with a code sent by RequestOTP. If no account has the phone number, one is created. Accounts
+// that haven't verified the phone number can't be logged in to, since anyone can register with any number. Users
+// with two factor authentication get a challenge, like in Login.
+func (s *AuthServiceServer) VerifyOTP(ctx context.Context, req *pb.VerifyOTPRequest) (*pb.VerifyOTPResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.OTP.Verify(ctx, req.PhoneNumber, req.Code); err != nil {
+		return nil, otpError(err)
+	}
+	newUser := false
+	user, err := s.Storage.GetUserByPhoneNumber(req.PhoneNumber)
+	if err != nil {
+		now := time.Now()
+		user = &storage.UserAccount{
+			PhoneNumber:     req.PhoneNumber,
+			PhoneVerifiedAt: &now,
+		}
+		if err := s.createUser(user); err != nil {
+			logrus.Errorln(err)
+			return nil, status.Error(codes.Internal, "could not create user")
+		}
+		newUser = true
+	} else if user.DisabledAt != nil {
+		return nil, errors.AccountDisabled
+	} else if user.PhoneVerifiedAt == nil {
+		return nil, status.Error(codes.FailedPrecondition,
+			"the account of this phone number hasn't verified it, log in another way and verify it first")
+	}
+	resp, err := s.login(ctx, user, req.DeviceName)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.VerifyOTPResponse{
+		AuthToken:         resp.AuthToken,
+		RefreshToken:      resp.RefreshToken,
+		NewUser:           newUser,
+		TwoFactorRequired: resp.TwoFactorRequired,
+		ChallengeToken:    resp.ChallengeToken,
+	}, nil
+}
+
+// otpError converts an error of the otp manager to a grpc status.
+func otpError(err error) error {
+	switch err {
+	case otp.ErrTooSoon, otp.ErrTooManyRequests:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case otp.ErrNoCode, otp.ErrTooManyAttempts:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case otp.ErrWrongCode:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	logrus.Errorln(err)
+	return errors.Internal
+}
+
+// StartOIDCLogin returns the login page of an OpenID Connect provider, see FinishOIDCLogin.
+func (s *AuthServiceServer) StartOIDCLogin(
+	ctx context.Context, req *pb.StartOIDCLoginRequest,
+) (*pb.StartOIDCLoginResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	authUrl, state, err := s.OIDC.Start(ctx, req.Provider, contextUserID(ctx))
+	if err != nil {
+		return nil, oidcError(err)
+	}
+	return &pb.StartOIDCLoginResponse{
+		AuthorizationUrl: authUrl,
+		State:            state,
+	}, nil
+}
+
+// FinishOIDCLogin logs in with the code an OpenID Connect provider redirected the user with. The provider account
+// is linked to the calling user if logged in, or else to the user with its email if the provider verified it, or
+// else to a new user. Users with two factor authentication get a challenge, like in Login.
+func (s *AuthServiceServer) FinishOIDCLogin(
+	ctx context.Context, req *pb.FinishOIDCLoginRequest,
+) (*pb.FinishOIDCLoginResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	claims, err := s.OIDC.Finish(ctx, req.Provider, req.Code, req.State, contextUserID(ctx))
+	if err != nil {
+		return nil, oidcError(err)
+	}
+	newUser := false
+	var user *storage.UserAccount
+	identity, err := s.Storage.GetUserIdentity(req.Provider, claims.Subject)
+	if err == nil {
+		user = &identity.User
+	} else {
+		user = GetContextUser(ctx)
+		if user != nil && user.IsGuest() {
+			user = nil
+		}
+		if user == nil {
+			// an unverified email may have been registered by someone else to take over the account once linked
+			user, err = s.Storage.GetUserByEmail(claims.Email)
+			if err == nil && (!claims.EmailVerified || user.EmailVerifiedAt == nil) {
+				return nil, status.Error(codes.AlreadyExists, "email already exists, log in to link the account")
+			}
+		}
+		if user == nil {
+			user = &storage.UserAccount{
+				FirstName: claims.GivenName,
+				LastName:  claims.FamilyName,
+			}
+			if claims.EmailVerified {
+				now := time.Now()
+				user.Email = claims.Email
+				user.EmailVerifiedAt = &now
+			}
+			if err := s.createUser(user); err != nil {
+				logrus.Errorln(err)
+				return nil, status.Error(codes.Internal, "could not create user")
+			}
+			newUser = true
+		}
+		err = s.Storage.CreateUserIdentity(&storage.UserIdentity{
+			UserID:   user.ID,
+			Provider: req.Provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		})
+		if err != nil {
+			logrus.Errorln(err)
+			return nil, status.Error(codes.Internal, "could not link the account")
+		}
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.AccountDisabled
+	}
+	resp, err := s.login(ctx, user, req.DeviceName)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FinishOIDCLoginResponse{
+		AuthToken:         resp.AuthToken,
+		RefreshToken:      resp.RefreshToken,
+		NewUser:           newUser,
+		TwoFactorRequired: resp.TwoFactorRequired,
+		ChallengeToken:    resp.ChallengeToken,
+	}, nil
+}
+
+// oidcError converts an error of an OpenID Connect login to a grpc status.
+func oidcError(err error) error {
+	switch err {
+	case oidc.ErrUnknownProvider:
+		return status.Error(codes.NotFound, err.Error())
+	case oidc.ErrInvalidState:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	logrus.Errorln(err)
+	return status.Error(codes.Unauthenticated, "could not log in with the provider")
+}
+
+// RefreshToken rotates a refresh token. Every refresh token can be used once; using it again revokes all tokens
+// of its session, since either the client or an attacker holds a stolen token.
 func (s *AuthServiceServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
-	claims, err := s.TokenManager.Validate(ctx, req.RefreshToken)
+	claims, err := s.TokenManager.ValidateType(ctx, req.RefreshToken, token.TypeRefresh)
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, "invalid token")
 	}
-	userId, ok := claims["userID"]
+	userId, ok := claims[token.ClaimUserID]
 	if !ok {
 		return nil, status.Error(codes.Unauthenticated, "no userId in token")
 	}
-	authToken, refreshToken, err := s.generateTokensWithID(userId)
+	sessionId, err := strconv.Atoi(claims[token.ClaimSession])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "no session in token")
+	}
+	refreshId := uuid.New().String()
+	rotated, err := s.Storage.RotateSessionToken(uint(sessionId), claims[token.ClaimID], refreshId, s.refreshExpiration())
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, status.Error(codes.Internal, "could not rotate refresh token")
+	}
+	if !rotated {
+		session, err := s.Storage.GetSessionByID(uint(sessionId))
+		if err != nil || !session.IsActive() {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		logrus.WithFields(logrus.Fields{
+			"user_id": userId,
+			"session": sessionId,
+		}).Warn("refresh token reuse detected, revoking its session")
+		if err := s.Storage.RevokeSession(session.UserID, session.ID); err != nil {
+			logrus.Errorln(err)
+		}
+		return nil, status.Error(codes.Unauthenticated, "refresh token is already used")
+	}
+	authToken, refreshToken, err := s.generateTokensWithID(userId, claims[token.ClaimSession], refreshId)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "could not generate tokens")
 	}
@@ -126,45 +556,331 @@ func (s *AuthServiceServer) UserInfo(ctx context.Context, req *pb.UserInfoReques
 	if user == nil {
 		return nil, errors.NotLoggedIn
 	}
+	return userInfo(user), nil
+}
+
+func userInfo(user *storage.UserAccount) *pb.UserInfoResponse {
 	return &pb.UserInfoResponse{
-		Email:       user.Email,
-		PhoneNumber: user.PhoneNumber,
-		Gender:      user.Gender,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-	}, nil
+		Email:         user.Email,
+		PhoneNumber:   user.PhoneNumber,
+		Gender:        user.Gender,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		PhoneVerified: user.PhoneVerifiedAt != nil,
+		Guest:         user.IsGuest(),
+	}
 }
 
+// Logout revokes the session of the given tokens.
 func (s *AuthServiceServer) Logout(ctx context.Context, req *pb.LogoutRequest) (*emptypb.Empty, error) {
-	err := s.TokenManager.InvalidateToken(ctx, req.AuthToken)
+	claims, err := s.TokenManager.ValidateType(ctx, req.RefreshToken, token.TypeRefresh)
+	if err != nil {
+		claims, err = s.TokenManager.ValidateType(ctx, req.AuthToken, token.TypeAccess)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	userId, err := strconv.Atoi(claims[token.ClaimUserID])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "no userId in token")
+	}
+	sessionId, err := strconv.Atoi(claims[token.ClaimSession])
 	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "no session in token")
+	}
+	if err := s.Storage.RevokeSession(uint(userId), uint(sessionId)); err != nil {
 		logrus.Errorln(err)
-		return nil, status.Error(codes.Internal, "could not invalidate auth token")
 	}
-	err = s.TokenManager.InvalidateToken(ctx, req.RefreshToken)
+	return &emptypb.Empty{}, nil
+}
+
+// ChangePassword sets a new password and revokes all other sessions of the user. A wrong current password counts as
+// a failed login.
+func (s *AuthServiceServer) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
 	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if err := s.checkPassword(ctx, user, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+	if err := s.setPassword(user.ID, req.NewPassword); err != nil {
+		return nil, err
+	}
+	if err := s.Storage.RevokeOtherSessions(user.ID, GetContextSession(ctx)); err != nil {
 		logrus.Errorln(err)
-		return nil, status.Error(codes.Internal, "could not invalidate refresh token")
 	}
 	return &emptypb.Empty{}, nil
 }
 
-func (s *AuthServiceServer) generateTokensWithID(userId string) (string, string, error) {
+// RequestPasswordReset emails a password reset link to the user. The email is sent in the background and the
+// call succeeds even if there is no such user or sending fails, so neither its response nor its timing tells who
+// is registered.
+func (s *AuthServiceServer) RequestPasswordReset(
+	ctx context.Context, req *pb.RequestPasswordResetRequest,
+) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if retryAfter := s.Guard.AllowPasswordReset(ctx, req.Email, getClientIP(ctx)); retryAfter > 0 {
+		return nil, retryError("too many password resets, try again later", retryAfter)
+	}
+	go s.sendPasswordReset(req.Email)
+	return &emptypb.Empty{}, nil
+}
+
+// sendPasswordReset creates a reset token for the user with the given email, if any, and emails its link.
+func (s *AuthServiceServer) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+	defer cancel()
+	user, err := s.Storage.GetUserByEmail(email)
+	if err != nil {
+		return
+	}
+	resetToken, err := randomToken()
+	if err != nil {
+		logrus.Errorln(err)
+		return
+	}
+	err = s.Storage.CreatePasswordResetToken(&storage.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(resetToken),
+		ExpiresAt: time.Now().Add(time.Second * time.Duration(s.PasswordResetExpire)),
+	})
+	if err != nil {
+		logrus.Errorln(err)
+		return
+	}
+	link := strings.ReplaceAll(s.PasswordResetUrl, "{token}", url.QueryEscape(resetToken))
+	body := fmt.Sprintf(
+		"Someone asked to reset the password of your Digivision account. To choose a new password, open\n%s\n"+
+			"The link expires in %d minutes. If it wasn't you, ignore this email.",
+		link, s.PasswordResetExpire/60,
+	)
+	if err := s.Mailer.Send(ctx, user.Email, "Reset your Digivision password", body); err != nil {
+		logrus.Errorln(err)
+	}
+}
+
+// ConfirmPasswordReset sets a new password using a reset token and revokes all sessions of the user.
+func (s *AuthServiceServer) ConfirmPasswordReset(
+	ctx context.Context, req *pb.ConfirmPasswordResetRequest,
+) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resetToken, err := s.Storage.UsePasswordResetToken(hashToken(req.Token))
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "invalid or expired reset token")
+	}
+	if err := s.setPassword(resetToken.UserID, req.NewPassword); err != nil {
+		return nil, err
+	}
+	if err := s.Storage.RevokeAllSessions(resetToken.UserID); err != nil {
+		logrus.Errorln(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServiceServer) setPassword(userId uint, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return status.Error(codes.Internal, "could not hash password")
+	}
+	if err := s.Storage.UpdateUserPassword(userId, string(hash)); err != nil {
+		logrus.Errorln(err)
+		return status.Error(codes.Internal, "could not update password")
+	}
+	return nil
+}
+
+// randomToken returns a random url safe token.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash of a random token to store instead of the token itself.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *AuthServiceServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	sessions, err := s.Storage.GetActiveSessionsByUserID(user.ID)
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	current := GetContextSession(ctx)
+	respSessions := make([]*pb.Session, 0)
+	for _, session := range sessions {
+		respSessions = append(respSessions, &pb.Session{
+			Id:         int32(session.ID),
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			Ip:         session.IP,
+			CreatedAt:  session.CreatedAt.Unix(),
+			LastSeenAt: session.LastSeenAt.Unix(),
+			Current:    session.ID == current,
+		})
+	}
+	return &pb.ListSessionsResponse{Sessions: respSessions}, nil
+}
+
+func (s *AuthServiceServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if err := s.Storage.RevokeSession(user.ID, uint(req.SessionId)); err != nil {
+		return nil, errors.NotFound
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServiceServer) RevokeAllOtherSessions(
+	ctx context.Context, req *pb.RevokeAllOtherSessionsRequest,
+) (*emptypb.Empty, error) {
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if err := s.Storage.RevokeOtherSessions(user.ID, GetContextSession(ctx)); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// SendVerificationCode sends a code to the email address or phone number of the user, to be checked by Verify.
+func (s *AuthServiceServer) SendVerificationCode(
+	ctx context.Context, req *pb.SendVerificationCodeRequest,
+) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if err := s.Verifier.Send(ctx, user, verificationChannel(req.Channel)); err != nil {
+		return nil, verificationError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServiceServer) Verify(ctx context.Context, req *pb.VerifyRequest) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if err := s.Verifier.Verify(ctx, user, verificationChannel(req.Channel), req.Code); err != nil {
+		return nil, verificationError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func verificationChannel(channel pb.VerificationChannel) string {
+	if channel == pb.VerificationChannel_PHONE {
+		return verification.ChannelPhone
+	}
+	return verification.ChannelEmail
+}
+
+// verificationError converts an error of the verifier to a grpc status.
+func verificationError(err error) error {
+	switch err {
+	case verification.ErrAlreadyVerified, verification.ErrMissingRecipient, verification.ErrNoCode:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case verification.ErrTooSoon:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case verification.ErrTooManyAttempts:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case verification.ErrWrongCode, verification.ErrUnknownChannel:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	logrus.Errorln(err)
+	return errors.Internal
+}
+
+func (s *AuthServiceServer) authExpiration() time.Time {
+	return time.Now().Add(time.Second * time.Duration(s.AuthTokenExpire))
+}
+
+func (s *AuthServiceServer) refreshExpiration() time.Time {
+	return time.Now().Add(time.Second * time.Duration(s.RefreshTokenExpire))
+}
+
+// generateTokensWithID returns an access token and a refresh token identified by refreshId, both of the given
+// session.
+func (s *AuthServiceServer) generateTokensWithID(userId string, sessionId string, refreshId string) (string, string, error) {
 	authToken, err := s.TokenManager.Generate(map[string]string{
-		"userID": userId,
-	}, time.Now().Add(time.Second*time.Duration(s.AuthTokenExpire)))
+		token.ClaimUserID:  userId,
+		token.ClaimType:    token.TypeAccess,
+		token.ClaimSession: sessionId,
+	}, s.authExpiration())
 	if err != nil {
 		return "", "", err
 	}
 	refreshToken, err := s.TokenManager.Generate(map[string]string{
-		"userID": userId,
-	}, time.Now().Add(time.Second*time.Duration(s.RefreshTokenExpire)))
+		token.ClaimUserID:  userId,
+		token.ClaimType:    token.TypeRefresh,
+		token.ClaimSession: sessionId,
+		token.ClaimID:      refreshId,
+	}, s.refreshExpiration())
 	if err != nil {
 		return "", "", err
 	}
 	return authToken, refreshToken, nil
 }
 
-func (s *AuthServiceServer) generateTokens(user *storage.UserAccount) (string, string, error) {
-	return s.generateTokensWithID(strconv.Itoa(int(user.ID)))
+// generateTokens starts a new session of the user on the calling device and returns its first access and refresh
+// tokens. If the call is made as a guest, e.g. a guest logging in, the data of the guest is moved to the user.
+func (s *AuthServiceServer) generateTokens(
+	ctx context.Context, user *storage.UserAccount, deviceName string,
+) (string, string, error) {
+	if guest := GetContextUser(ctx); guest != nil && guest.IsGuest() && !user.IsGuest() && guest.ID != user.ID {
+		if err := s.Storage.MergeGuest(guest.ID, user.ID); err != nil {
+			logrus.Errorln(err)
+		}
+	}
+	session := storage.Session{
+		UserID:         user.ID,
+		DeviceName:     deviceName,
+		UserAgent:      getUserAgent(ctx),
+		IP:             getClientIP(ctx),
+		LastSeenAt:     time.Now(),
+		CurrentTokenID: uuid.New().String(),
+		ExpiresAt:      s.refreshExpiration(),
+	}
+	if err := s.Storage.CreateSession(&session); err != nil {
+		return "", "", err
+	}
+	return s.generateTokensWithID(
+		strconv.Itoa(int(user.ID)), strconv.Itoa(int(session.ID)), session.CurrentTokenID,
+	)
 }
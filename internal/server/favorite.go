@@ -117,3 +117,27 @@ func (s *FavoriteServiceServer) GetFavorites(
 
 	return &pb.GetFavoritesResponse{Products: productsList}, nil
 }
+
+func (s *FavoriteServiceServer) UpdateFavoriteAlert(
+	ctx context.Context, req *pb.UpdateFavoriteAlertRequest,
+) (*pb.UpdateFavoriteAlertResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+
+	list, err := s.Storage.GetFavoriteListByUserIDAndName(user.ID, req.ListName)
+	if err != nil {
+		return nil, errors.NotFound
+	}
+	err = s.Storage.UpdateFavoriteItemAlert(list.ID, uint(req.ProductId), req.PriceDropPercent, req.BackInStock)
+	if err != nil {
+		return nil, errors.NotFound
+	}
+	return &pb.UpdateFavoriteAlertResponse{Success: true}, nil
+}
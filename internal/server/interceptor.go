@@ -3,56 +3,158 @@ package server
 import (
 	"context"
 	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/apikey"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/verification"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type AuthInterceptor struct {
 	storage      *storage.Storage
 	tokenManager token.Manager
+	verification verification.Config
+	apiKeys      *apikey.Manager
 }
 
-func NewAuthInterceptor(storage *storage.Storage, tokenManager token.Manager) *AuthInterceptor {
+func NewAuthInterceptor(
+	storage *storage.Storage,
+	tokenManager token.Manager,
+	verificationConfig verification.Config,
+	apiKeys *apikey.Manager,
+) *AuthInterceptor {
 	return &AuthInterceptor{
 		storage:      storage,
 		tokenManager: tokenManager,
+		verification: verificationConfig,
+		apiKeys:      apiKeys,
 	}
 }
 
+// methodFeature returns the feature a method belongs to, which may be restricted to verified users.
+func methodFeature(method string) string {
+	switch {
+	case strings.HasPrefix(method, "/v1.FavoriteService/"):
+		return verification.FeatureFavorites
+	case method == "/v1.SearchService/GetSearchHistories":
+		return verification.FeatureHistory
+	}
+	return ""
+}
+
+// sessionTouchInterval is how often the last seen time of a session in use is updated.
+const sessionTouchInterval = time.Minute
+
 type authUserKey struct{}
 
+type authSessionKey struct{}
+
+type authApiKeyKey struct{}
+
 func (i *AuthInterceptor) InterceptServer() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler) (interface{}, error) {
 
-		accessToken := getMetadataValue(ctx, "x-access-token")
-		if len(accessToken) != 0 {
-			logrus.Debug("Using access token: ", accessToken)
-			claims, err := i.tokenManager.Validate(ctx, accessToken)
-			if err != nil {
-				return nil, errors.InvalidAccessToken
-			}
-			userId, ok := claims["userID"]
-			if !ok {
-				return nil, errors.InvalidAccessToken
-			}
-			id, err := strconv.Atoi(userId)
-			if err != nil {
-				return nil, errors.InvalidAccessToken
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// InterceptStream authenticates streaming rpcs like InterceptServer does unary ones. Over the websocket proxy, the
+// token comes from the "Bearer, <token>" subprotocol, the token cookie or the access_token query parameter, which
+// RunHttpServer turns into the Authorization header.
+func (i *AuthInterceptor) InterceptStream() grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		ctx, err := i.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticate attaches the user of the access token, or the api key, of a call to its context.
+func (i *AuthInterceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	accessToken := strings.TrimPrefix(getMetadataValue(ctx, "x-access-token"), "Bearer ")
+	if len(accessToken) != 0 {
+		logrus.Debug("Using access token: ", accessToken)
+		claims, err := i.tokenManager.ValidateType(ctx, accessToken, token.TypeAccess)
+		if err != nil {
+			return nil, errors.InvalidAccessToken
+		}
+		userId, ok := claims[token.ClaimUserID]
+		if !ok {
+			return nil, errors.InvalidAccessToken
+		}
+		sessionId, err := strconv.Atoi(claims[token.ClaimSession])
+		if err != nil {
+			return nil, errors.InvalidAccessToken
+		}
+		session, err := i.storage.GetSessionByID(uint(sessionId))
+		if err != nil || !session.IsActive() || strconv.Itoa(int(session.UserID)) != userId {
+			return nil, errors.InvalidAccessToken
+		}
+		if time.Since(session.LastSeenAt) > sessionTouchInterval {
+			if err := i.storage.TouchSession(session.ID, getClientIP(ctx)); err != nil {
+				logrus.Errorln(err)
 			}
-			user, err := i.storage.GetUserByID(uint(id))
+		}
 
-			ctx = AttachUserToCtx(ctx, user)
+		if session.User.DisabledAt != nil {
+			return nil, errors.AccountDisabled
+		}
+		feature := methodFeature(method)
+		if feature != "" && !i.verification.Allows(&session.User, feature) {
+			return nil, errors.NotVerified
 		}
 
-		return handler(ctx, req)
+		ctx = AttachUserToCtx(ctx, &session.User)
+		ctx = AttachSessionToCtx(ctx, session.ID)
+	} else if apiKey := getMetadataValue(ctx, "x-api-key"); len(apiKey) != 0 {
+		key, err := i.apiKeys.Authenticate(apiKey)
+		if err == apikey.ErrInvalidKey {
+			return nil, errors.InvalidApiKey
+		} else if err != nil {
+			logrus.Error("failed to authenticate api key: ", err)
+			return nil, errors.Unavailable
+		}
+		if !key.Allows(method) {
+			return nil, errors.PermissionDenied
+		}
+		if err := i.apiKeys.Allow(ctx, key); err != nil {
+			return nil, errors.RateLimited
+		}
+		i.apiKeys.RecordUsage(ctx, key, method)
+
+		ctx = AttachApiKeyToCtx(ctx, key)
 	}
+	return ctx, nil
+}
+
+// contextStream is a server stream with the context an interceptor attached values to.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
 }
 
 func AttachUserToCtx(ctx context.Context, user *storage.UserAccount) context.Context {
@@ -71,6 +173,65 @@ func GetContextUser(ctx context.Context) *storage.UserAccount {
 	return &user
 }
 
+func AttachSessionToCtx(ctx context.Context, sessionID uint) context.Context {
+	return context.WithValue(ctx, authSessionKey{}, sessionID)
+}
+
+// GetContextSession returns the id of the session of the access token of the request, or zero if there is none.
+func GetContextSession(ctx context.Context) uint {
+	sessionID, _ := ctx.Value(authSessionKey{}).(uint)
+	return sessionID
+}
+
+// AttachApiKeyToCtx attaches the api key a partner calls with, which is the principal of the request instead of a
+// user.
+func AttachApiKeyToCtx(ctx context.Context, key *storage.ApiKey) context.Context {
+	return context.WithValue(ctx, authApiKeyKey{}, *key)
+}
+
+// GetContextApiKey returns the api key of the request, or nil if it is not made with one.
+func GetContextApiKey(ctx context.Context) *storage.ApiKey {
+	key, ok := ctx.Value(authApiKeyKey{}).(storage.ApiKey)
+	if !ok {
+		return nil
+	}
+	return &key
+}
+
+// getUserAgent returns the user agent of the client, which the http gateway forwards with a prefix.
+func getUserAgent(ctx context.Context) string {
+	if userAgent := getMetadataValue(ctx, "grpcgateway-user-agent"); userAgent != "" {
+		return userAgent
+	}
+	return getMetadataValue(ctx, "user-agent")
+}
+
+// getClientIP returns the address of the client. The http gateway calls over loopback and appends the address of
+// the http client to x-forwarded-for, so only that last entry is used; the ones before it are set by the client.
+// For other calls the metadata is set by the client, so the address of the grpc peer is used.
+func getClientIP(ctx context.Context) string {
+	host := peerIP(ctx)
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if forwarded := getMetadataValue(ctx, "x-forwarded-for"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	return host
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
 func getMetadataValue(ctx context.Context, key string) string {
 	md, _ := metadata.FromIncomingContext(ctx)
 	if md == nil {
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type NotificationServiceServer struct {
+	pb.UnimplementedNotificationServiceServer
+	Storage *storage.Storage
+}
+
+func NewNotificationServiceServer(storage *storage.Storage) *NotificationServiceServer {
+	return &NotificationServiceServer{
+		Storage: storage,
+	}
+}
+
+func (s *NotificationServiceServer) GetNotifications(
+	ctx context.Context, req *pb.GetNotificationsRequest,
+) (*pb.GetNotificationsResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+
+	notifications, unread, err := s.Storage.GetNotificationsByUserID(
+		user.ID, int(req.Offset), int(req.Limit), req.UnreadOnly,
+	)
+	if err != nil {
+		return nil, errors.Internal
+	}
+	respNotifications := make([]*pb.Notification, 0)
+	for _, notification := range notifications {
+		respNotifications = append(respNotifications, &pb.Notification{
+			Id:        int32(notification.ID),
+			Kind:      notification.Kind,
+			ProductId: int32(notification.ProductID),
+			Title:     notification.Title,
+			Body:      notification.Body,
+			OldPrice:  notification.OldPrice,
+			NewPrice:  notification.NewPrice,
+			CreatedAt: notification.CreatedAt.Unix(),
+			Read:      notification.ReadAt != nil,
+		})
+	}
+	return &pb.GetNotificationsResponse{Notifications: respNotifications, UnreadCount: int32(unread)}, nil
+}
+
+func (s *NotificationServiceServer) MarkNotificationsRead(
+	ctx context.Context, req *pb.MarkNotificationsReadRequest,
+) (*pb.MarkNotificationsReadResponse, error) {
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+
+	ids := make([]uint, len(req.Ids))
+	for i, id := range req.Ids {
+		ids[i] = uint(id)
+	}
+	if err := s.Storage.MarkNotificationsRead(user.ID, ids); err != nil {
+		return nil, errors.Internal
+	}
+	return &pb.MarkNotificationsReadResponse{Success: true}, nil
+}
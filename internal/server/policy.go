@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rbac"
+	"google.golang.org/grpc"
+)
+
+// policy declares who may call each rpc of our services. Rpcs of our services missing here are denied, so a new rpc
+// has to be added before it can be called.
+var policy = map[string]rbac.Rule{
+	"/v1.AuthService/Login":                  rbac.Public,
+	"/v1.AuthService/RefreshToken":           rbac.Public,
+	"/v1.AuthService/Logout":                 rbac.Public,
+	"/v1.AuthService/Register":               rbac.Public,
+	"/v1.AuthService/UserInfo":               rbac.Guests,
+	"/v1.AuthService/ChangePassword":         rbac.Authenticated,
+	"/v1.AuthService/RequestPasswordReset":   rbac.Public,
+	"/v1.AuthService/ConfirmPasswordReset":   rbac.Public,
+	"/v1.AuthService/ListSessions":           rbac.Authenticated,
+	"/v1.AuthService/RevokeSession":          rbac.Authenticated,
+	"/v1.AuthService/RevokeAllOtherSessions": rbac.Authenticated,
+	"/v1.AuthService/SendVerificationCode":   rbac.Authenticated,
+	"/v1.AuthService/Verify":                 rbac.Authenticated,
+	"/v1.AuthService/RequestOTP":             rbac.Public,
+	"/v1.AuthService/VerifyOTP":              rbac.Public,
+	"/v1.AuthService/StartOIDCLogin":         rbac.Public,
+	"/v1.AuthService/FinishOIDCLogin":        rbac.Public,
+	"/v1.AuthService/CreateGuest":            rbac.Public,
+	"/v1.AuthService/UpdateProfile":          rbac.Authenticated,
+	"/v1.AuthService/ExportMyData":           rbac.Authenticated,
+	"/v1.AuthService/DeleteAccount":          rbac.Authenticated,
+	"/v1.AuthService/EnrollTwoFactor":        rbac.Authenticated,
+	"/v1.AuthService/ConfirmTwoFactor":       rbac.Authenticated,
+	"/v1.AuthService/DisableTwoFactor":       rbac.Authenticated,
+	"/v1.AuthService/VerifyTwoFactor":        rbac.Public,
+
+	"/v1.SearchService/Search":             rbac.Public,
+	"/v1.SearchService/AsyncSearch":        rbac.Public,
+	"/v1.SearchService/Crop":               rbac.Public,
+	"/v1.SearchService/GetProduct":         rbac.Public,
+	"/v1.SearchService/GetPriceHistory":    rbac.Public,
+	"/v1.SearchService/GetSearchHistories": rbac.Guests,
+
+	"/v1.FavoriteService/AddItemToFavorites":      rbac.Guests,
+	"/v1.FavoriteService/RemoveItemFromFavorites": rbac.Guests,
+	"/v1.FavoriteService/GetFavorites":            rbac.Guests,
+	"/v1.FavoriteService/UpdateFavoriteAlert":     rbac.Guests,
+
+	"/v1.NotificationService/GetNotifications":      rbac.Guests,
+	"/v1.NotificationService/MarkNotificationsRead": rbac.Guests,
+
+	"/v1.AdminService/ListUsers":          rbac.Requires(rbac.PermUsersRead),
+	"/v1.AdminService/GetUser":            rbac.Requires(rbac.PermUsersRead),
+	"/v1.AdminService/SetUserRole":        rbac.Requires(rbac.PermUsersWrite),
+	"/v1.AdminService/DisableUser":        rbac.Requires(rbac.PermUsersWrite),
+	"/v1.AdminService/EnableUser":         rbac.Requires(rbac.PermUsersWrite),
+	"/v1.AdminService/RevokeUserSessions": rbac.Requires(rbac.PermSessionsRevoke),
+	"/v1.AdminService/GetUsage":           rbac.Requires(rbac.PermUsageRead),
+	"/v1.AdminService/CreateApiKey":       rbac.Requires(rbac.PermApiKeysWrite),
+	"/v1.AdminService/ListApiKeys":        rbac.Requires(rbac.PermApiKeysWrite),
+	"/v1.AdminService/RevokeApiKey":       rbac.Requires(rbac.PermApiKeysWrite),
+	"/v1.AdminService/GetApiKeyUsage":     rbac.Requires(rbac.PermUsageRead),
+}
+
+// apiKeyScopeAllowed reports whether api keys may be scoped to a method. Keys carry no user, so only public rpcs
+// can be called with them.
+func apiKeyScopeAllowed(method string) bool {
+	rule, ok := policy[method]
+	return ok && !rule.Authenticated
+}
+
+// PolicyInterceptor enforces the rpc policy. It runs after AuthInterceptor, which attaches the calling user.
+type PolicyInterceptor struct{}
+
+func NewPolicyInterceptor() *PolicyInterceptor {
+	return &PolicyInterceptor{}
+}
+
+func (i *PolicyInterceptor) InterceptServer() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if err := authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *PolicyInterceptor) InterceptStream() grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		if err := authorize(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// authorize checks the policy of a method for the calling user. Methods of other services, e.g. health checks, are
+// not restricted.
+func authorize(ctx context.Context, method string) error {
+	rule, ok := policy[method]
+	if !ok {
+		if strings.HasPrefix(method, "/v1.") {
+			logrus.Errorf("no policy for %s, denying", method)
+			return errors.PermissionDenied
+		}
+		return nil
+	}
+	if !rule.Authenticated {
+		return nil
+	}
+	user := GetContextUser(ctx)
+	if user == nil || (user.IsGuest() && !rule.Guests) {
+		return errors.NotLoggedIn
+	}
+	if rule.Permission != "" && !rbac.Has(user.Role, rule.Permission) {
+		return errors.PermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/s3"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/verification"
+	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
+	"time"
+)
+
+// exportChunkSize is the size of the chunks a data export is streamed in, well below the message size limits.
+const exportChunkSize = 1 << 20
+
+// UpdateProfile changes the name, gender and phone number of the user. A new phone number is unverified until the
+// code sent to it is entered.
+func (s *AuthServiceServer) UpdateProfile(
+	ctx context.Context, req *pb.UpdateProfileRequest,
+) (*pb.UserInfoResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if req.FirstName != "" {
+		user.FirstName = req.FirstName
+	}
+	if req.LastName != "" {
+		user.LastName = req.LastName
+	}
+	if req.Gender != "" {
+		user.Gender = req.Gender
+	}
+	phoneChanged := req.PhoneNumber != "" && req.PhoneNumber != user.PhoneNumber
+	if phoneChanged {
+		if _, err := s.Storage.GetUserByPhoneNumber(req.PhoneNumber); err == nil {
+			return nil, status.Error(codes.AlreadyExists, "phone number already exists")
+		}
+		user.PhoneNumber = req.PhoneNumber
+		user.PhoneVerifiedAt = nil
+	}
+	if err := s.Storage.UpdateUserProfile(user); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	if phoneChanged {
+		if err := s.Verifier.Send(ctx, user, verification.ChannelPhone); err != nil {
+			logrus.Error("failed to send verification code to the new phone number: ", err)
+		}
+	}
+	return userInfo(user), nil
+}
+
+// exportedData is the data.json file of a data export.
+type exportedData struct {
+	ExportedAt    time.Time              `json:"exported_at"`
+	Profile       exportedProfile        `json:"profile"`
+	FavoriteLists []exportedFavoriteList `json:"favorite_lists"`
+	SearchHistory []exportedSearch       `json:"search_history"`
+	Sessions      []exportedSession      `json:"sessions"`
+	Notifications []exportedNotification `json:"notifications"`
+	Identities    []exportedIdentity     `json:"linked_accounts"`
+}
+
+type exportedProfile struct {
+	Email           string     `json:"email"`
+	PhoneNumber     string     `json:"phone_number"`
+	Gender          string     `json:"gender"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Role            string     `json:"role"`
+	CreatedAt       time.Time  `json:"created_at"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at"`
+}
+
+type exportedFavoriteList struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Products  []uint    `json:"product_ids"`
+}
+
+type exportedSearch struct {
+	SearchedAt time.Time `json:"searched_at"`
+	// Image is the path of the query image in the archive, empty if it couldn't be exported.
+	Image    string `json:"image"`
+	Products []uint `json:"product_ids"`
+}
+
+type exportedSession struct {
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type exportedNotification struct {
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+type exportedIdentity struct {
+	Provider string `json:"provider"`
+	Email    string `json:"email"`
+}
+
+// ExportMyData streams a zip archive with all data stored about the user in data.json and the query images of
+// their search history in images/. The archive is sent in chunks as it's written, so it's never held in memory as
+// a whole.
+func (s *AuthServiceServer) ExportMyData(req *pb.ExportMyDataRequest, stream pb.AuthService_ExportMyDataServer) error {
+	ctx := stream.Context()
+	user := GetContextUser(ctx)
+	if user == nil {
+		return errors.NotLoggedIn
+	}
+	data, err := s.Storage.GetUserData(user.ID)
+	if err != nil {
+		logrus.Errorln(err)
+		return errors.Internal
+	}
+
+	out := &exportWriter{stream: stream, buf: make([]byte, 0, exportChunkSize)}
+	archive := zip.NewWriter(out)
+	export := exportedData{
+		ExportedAt: time.Now(),
+		Profile: exportedProfile{
+			Email:           data.User.Email,
+			PhoneNumber:     data.User.PhoneNumber,
+			Gender:          data.User.Gender,
+			FirstName:       data.User.FirstName,
+			LastName:        data.User.LastName,
+			Role:            data.User.Role,
+			CreatedAt:       data.User.CreatedAt,
+			EmailVerifiedAt: data.User.EmailVerifiedAt,
+			PhoneVerifiedAt: data.User.PhoneVerifiedAt,
+		},
+		FavoriteLists: make([]exportedFavoriteList, 0, len(data.FavoriteLists)),
+		SearchHistory: make([]exportedSearch, 0, len(data.SearchHistories)),
+		Sessions:      make([]exportedSession, 0, len(data.Sessions)),
+		Notifications: make([]exportedNotification, 0, len(data.Notifications)),
+		Identities:    make([]exportedIdentity, 0, len(data.Identities)),
+	}
+	for _, list := range data.FavoriteLists {
+		products := make([]uint, 0, len(list.Items))
+		for _, item := range list.Items {
+			products = append(products, item.ProductID)
+		}
+		export.FavoriteLists = append(export.FavoriteLists, exportedFavoriteList{
+			Name:      list.Name,
+			CreatedAt: list.CreatedAt,
+			Products:  products,
+		})
+	}
+	for _, history := range data.SearchHistories {
+		products := make([]uint, 0, len(history.Results))
+		for _, result := range history.Results {
+			products = append(products, result.ProductID)
+		}
+		image := ""
+		if history.QueryAddress != "" {
+			image = "images/" + history.QueryAddress
+			if err := s.exportImage(ctx, archive, image, history.QueryAddress); err != nil {
+				logrus.Errorf("failed to export history image %s: %v", history.QueryAddress, err)
+				image = ""
+			}
+		}
+		export.SearchHistory = append(export.SearchHistory, exportedSearch{
+			SearchedAt: history.CreatedAt,
+			Image:      image,
+			Products:   products,
+		})
+	}
+	for _, session := range data.Sessions {
+		export.Sessions = append(export.Sessions, exportedSession{
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+	for _, notification := range data.Notifications {
+		export.Notifications = append(export.Notifications, exportedNotification{
+			Title:     notification.Title,
+			Body:      notification.Body,
+			CreatedAt: notification.CreatedAt,
+			ReadAt:    notification.ReadAt,
+		})
+	}
+	for _, identity := range data.Identities {
+		export.Identities = append(export.Identities, exportedIdentity{
+			Provider: identity.Provider,
+			Email:    identity.Email,
+		})
+	}
+
+	file, err := archive.Create("data.json")
+	if err == nil {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(export)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err == nil {
+		err = out.flush()
+	}
+	if err != nil {
+		logrus.Error("failed to write data export: ", err)
+		return errors.Internal
+	}
+	return nil
+}
+
+// exportWriter sends what is written to it to a data export stream in chunks of exportChunkSize.
+type exportWriter struct {
+	stream pb.AuthService_ExportMyDataServer
+	buf    []byte
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush sends the buffered part of the archive.
+func (w *exportWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.stream.Send(&httpbody.HttpBody{
+		ContentType: "application/zip",
+		Data:        w.buf,
+	})
+	// the message is marshalled by Send, so the buffer can be reused
+	w.buf = w.buf[:0]
+	return err
+}
+
+// exportImage copies a query image from s3 into the archive.
+func (s *AuthServiceServer) exportImage(ctx context.Context, archive *zip.Writer, name string, path string) error {
+	image, err := s.S3Client.Download(ctx, s3.HistoryImagesBucket, path)
+	if err != nil {
+		return err
+	}
+	defer image.Close()
+	// read the image first, so a missing image doesn't leave an empty file in the archive
+	content, err := io.ReadAll(image)
+	if err != nil {
+		return err
+	}
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
+
+// DeleteAccount deletes the user for good: their rows in all tables, the query images of their search history and,
+// since their sessions go too, all their tokens. Users with a password have to confirm it, wrong ones count as
+// failed logins.
+func (s *AuthServiceServer) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*emptypb.Empty, error) {
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	if user.PasswordHash != "" {
+		if err := s.checkPassword(ctx, user, req.Password); err != nil {
+			return nil, err
+		}
+	}
+	// the images go first: if removing one fails the account is kept, so deleting it again removes the rest
+	paths, err := s.Storage.GetSearchHistoryImages(user.ID)
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	for _, path := range paths {
+		if err := s.S3Client.Remove(ctx, s3.HistoryImagesBucket, path); err != nil {
+			logrus.Errorf("failed to remove history image %s: %v", path, err)
+			return nil, errors.Internal
+		}
+	}
+	if err := s.Storage.DeleteUser(user.ID); err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	logrus.WithField("user_id", user.ID).Info("account deleted")
+	return &emptypb.Empty{}, nil
+}
@@ -13,21 +13,32 @@ import (
 	"github.com/tmc/grpc-websocket-proxy/wsproxy"
 	img2vecPb "github.com/web-programming-fall-2022/digivision-backend/internal/api/img2vec"
 	odPb "github.com/web-programming-fall-2022/digivision-backend/internal/api/od"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/apikey"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/bootstrap"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/bootstrap/job"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/captcha"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/cfg"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/img2vec"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/loginguard"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/od"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/oidc"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/otp"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/productmeta"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/resultcache"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/s3"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/search"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/sms"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/twofactor"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/verification"
 	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
 	"log"
 	"math"
 	"net/http"
@@ -35,7 +46,9 @@ import (
 	"time"
 )
 
-func RunServer(ctx context.Context, config cfg.Config) job.WithGracefulShutdown {
+// RunServer starts the grpc server. It also returns the key set signing its tokens, whose public keys the http
+// server publishes.
+func RunServer(ctx context.Context, config cfg.Config) (job.WithGracefulShutdown, *token.KeySet) {
 	// Create the Img2Vec service
 	img2vecConnection, err := grpc.Dial(config.Img2Vec.Addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -103,15 +116,15 @@ func RunServer(ctx context.Context, config cfg.Config) job.WithGracefulShutdown
 		DB:       0,  // use default DB
 	})
 
-	httpClient := resty.New()
-	fetcher := productmeta.NewDigikalaFetcher(
-		"https://www.digikala.com",
-		"https://api.digikala.com/v1/product",
-		httpClient,
-		rdb,
-		3,
-		5,
-	)
+	var resultCache resultcache.Cache = resultcache.NoopCache{}
+	if config.SearchCache.Enabled {
+		resultCache = resultcache.NewRedisCache(
+			rdb,
+			config.Milvus.CollectionName,
+			time.Duration(config.SearchCache.TTL)*time.Second,
+			config.SearchCache.QuantizationStep,
+		)
+	}
 
 	store := storage.NewStorage(&config.MainDB)
 
@@ -119,17 +132,41 @@ func RunServer(ctx context.Context, config cfg.Config) job.WithGracefulShutdown
 		log.Fatal(err)
 	}
 
-	tokenManager := token.NewJWTManager(config.JWT.Secret, store, rdb)
+	httpClient := resty.New()
+	fetcher, err := productmeta.NewFetcher(config.Catalog, httpClient, rdb, store)
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+
+	keys, err := token.NewKeySet(store, config.JWT)
+	if err != nil {
+		logrus.Fatal("failed to create signing key set: ", err)
+	}
+	// makes sure there is a signing key before serving, the key rotation job adds the next ones
+	if err := keys.Rotate(); err != nil {
+		logrus.Fatal("failed to rotate signing keys: ", err)
+	}
+	tokenManager := token.NewJWTManager(keys, rdb)
 
 	s3Client, err := s3.NewMinioClient(config.S3.Endpoint, config.S3.AccessKey, config.S3.SecretKey, config.S3.UseSSL)
 	if err != nil {
 		logrus.Fatal(err.Error())
 	}
 
+	apiKeys := apikey.NewManager(store, rdb)
+
+	authInterceptor := NewAuthInterceptor(store, tokenManager, config.Verification, apiKeys)
+	policyInterceptor := NewPolicyInterceptor()
 	serverRunner, err := bootstrap.NewGrpcServerRunner(
 		config.GrpcServerRunnerConfig,
-		[]grpc.UnaryServerInterceptor{NewAuthInterceptor(store, tokenManager).InterceptServer()},
-		nil,
+		[]grpc.UnaryServerInterceptor{
+			authInterceptor.InterceptServer(),
+			policyInterceptor.InterceptServer(),
+		},
+		[]grpc.StreamServerInterceptor{
+			authInterceptor.InterceptStream(),
+			policyInterceptor.InterceptStream(),
+		},
 	)
 	if err != nil {
 		logrus.Fatal(err.Error())
@@ -137,12 +174,40 @@ func RunServer(ctx context.Context, config cfg.Config) job.WithGracefulShutdown
 	// Create the gRPC server
 	grpcServer := serverRunner.GetGrpcServer()
 
-	registerSearchServer(grpcServer, i2v, searchHandler, fetcher, rankers, objectDetector, s3Client, store)
+	registerSearchServer(grpcServer, i2v, searchHandler, fetcher, rankers, objectDetector, s3Client, store, resultCache,
+		config.Verification)
+
+	mailer, err := mail.NewMailer(config.Mail)
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+	smsSender, err := sms.NewSender(config.Sms)
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+	captchaVerifier, err := captcha.NewVerifier(config.Captcha)
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+	twoFactorManager, err := twofactor.NewManager(store, config.TwoFactor)
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
 
 	registerAuthServer(
 		grpcServer, tokenManager, store,
 		config.JWT.AuthTokenExpire,
 		config.JWT.RefreshTokenExpire,
+		mailer,
+		config.PasswordReset.TokenExpire,
+		config.PasswordReset.Url,
+		verification.NewVerifier(store, mailer, smsSender, config.Verification),
+		otp.NewManager(rdb, smsSender, config.Otp),
+		oidc.NewManager(config.Oidc, httpClient, rdb),
+		loginguard.NewGuard(rdb, config.LoginGuard),
+		captchaVerifier,
+		s3Client,
+		twoFactorManager,
 	)
 
 	registerFavoriteServer(
@@ -151,13 +216,17 @@ func RunServer(ctx context.Context, config cfg.Config) job.WithGracefulShutdown
 		fetcher,
 	)
 
+	registerNotificationServer(grpcServer, store)
+
+	registerAdminServer(grpcServer, store, apiKeys)
+
 	go func() {
 		logrus.Infoln("Starting grpc server...")
 		if err := serverRunner.Run(ctx); err != nil {
 			logrus.Fatal(err.Error())
 		}
 	}()
-	return serverRunner
+	return serverRunner, keys
 }
 
 func registerSearchServer(
@@ -169,6 +238,8 @@ func registerSearchServer(
 	objectDetector od.ObjectDetector,
 	s3Client s3.Client,
 	store *storage.Storage,
+	resultCache resultcache.Cache,
+	verificationConfig verification.Config,
 ) {
 	pb.RegisterSearchServiceServer(server, NewSearchServiceServer(
 		i2v,
@@ -178,6 +249,8 @@ func registerSearchServer(
 		objectDetector,
 		s3Client,
 		store,
+		resultCache,
+		verificationConfig,
 	))
 }
 
@@ -187,12 +260,32 @@ func registerAuthServer(
 	storage *storage.Storage,
 	authTokenExpire int64,
 	refreshTokenExpire int64,
+	mailer mail.Mailer,
+	passwordResetExpire int64,
+	passwordResetUrl string,
+	verifier *verification.Verifier,
+	otpManager *otp.Manager,
+	oidcManager *oidc.Manager,
+	guard *loginguard.Guard,
+	captchaVerifier captcha.Verifier,
+	s3Client s3.Client,
+	twoFactorManager *twofactor.Manager,
 ) {
 	pb.RegisterAuthServiceServer(server, NewAuthServiceServer(
 		tokenManager,
 		storage,
 		authTokenExpire,
 		refreshTokenExpire,
+		mailer,
+		passwordResetExpire,
+		passwordResetUrl,
+		verifier,
+		otpManager,
+		oidcManager,
+		guard,
+		captchaVerifier,
+		s3Client,
+		twoFactorManager,
 	))
 }
 
@@ -204,14 +297,38 @@ func registerFavoriteServer(
 	pb.RegisterFavoriteServiceServer(server, NewFavoriteServiceServer(storage, fetcher))
 }
 
-func RunHttpServer(ctx context.Context, config cfg.Config) job.WithGracefulShutdown {
+func registerNotificationServer(
+	server *grpc.Server,
+	storage *storage.Storage,
+) {
+	pb.RegisterNotificationServiceServer(server, NewNotificationServiceServer(storage))
+}
+
+func registerAdminServer(
+	server *grpc.Server,
+	storage *storage.Storage,
+	apiKeys *apikey.Manager,
+) {
+	pb.RegisterAdminServiceServer(server, NewAdminServiceServer(storage, apiKeys))
+}
+
+func RunHttpServer(ctx context.Context, config cfg.Config, keys *token.KeySet) job.WithGracefulShutdown {
 	mux := runtime.NewServeMux(
 		runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
 			if key == "Authorization" {
 				return "x-access-token", true
 			}
+			if key == "X-Api-Key" {
+				return "x-api-key", true
+			}
 			return runtime.DefaultHeaderMatcher(key)
 		}),
+		runtime.WithMarshalerOption(archiveMIME, &archiveMarshaler{runtime.HTTPBodyMarshaler{
+			Marshaler: &runtime.JSONPb{
+				MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+				UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+			},
+		}}),
 	)
 	opts := []grpc.DialOption{
 		grpc.WithDefaultCallOptions(
@@ -229,10 +346,36 @@ func RunHttpServer(ctx context.Context, config cfg.Config) job.WithGracefulShutd
 	if err := pb.RegisterFavoriteServiceHandlerFromEndpoint(ctx, mux, fmt.Sprintf("localhost:%d", config.Server.Port), opts); err != nil {
 		logrus.Fatal("Failed to start HTTP gateway for favorite", err.Error())
 	}
+	if err := pb.RegisterNotificationServiceHandlerFromEndpoint(ctx, mux, fmt.Sprintf("localhost:%d", config.Server.Port), opts); err != nil {
+		logrus.Fatal("Failed to start HTTP gateway for notification", err.Error())
+	}
+	if err := pb.RegisterAdminServiceHandlerFromEndpoint(ctx, mux, fmt.Sprintf("localhost:%d", config.Server.Port), opts); err != nil {
+		logrus.Fatal("Failed to start HTTP gateway for admin", err.Error())
+	}
 
+	err := mux.HandlePath(http.MethodGet, "/.well-known/jwks.json",
+		func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			jwks, err := keys.JWKS()
+			if err != nil {
+				logrus.Error("failed to load signing keys: ", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "public, max-age=300")
+			_, _ = w.Write(jwks)
+		})
+	if err != nil {
+		logrus.Fatal("Failed to register jwks handler", err.Error())
+	}
+
+	handler := wsproxy.WebsocketProxy(cors(archiveRequest(mux)),
+		wsproxy.WithForwardedHeaders(websocketForwardedHeader),
+		wsproxy.WithRequestMutator(websocketRequest),
+	)
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.HttpServer.Port),
-		Handler: wsproxy.WebsocketProxy(cors(mux)),
+		Handler: handler,
 	}
 
 	logrus.Info("Starting HTTP/REST Gateway...", srv.Addr)
@@ -245,6 +388,34 @@ func RunHttpServer(ctx context.Context, config cfg.Config) job.WithGracefulShutd
 	return srv
 }
 
+// websocketForwardedHeader reports whether a header of a websocket handshake is forwarded to the gateway. Besides
+// the defaults of wsproxy, partners may stream with their api key.
+func websocketForwardedHeader(header string) bool {
+	switch http.CanonicalHeaderKey(header) {
+	case "Origin", "Referer", "User-Agent", "X-Forwarded-For", "X-Api-Key":
+		return true
+	}
+	return false
+}
+
+// websocketRequest passes the access_token query parameter of a websocket handshake as the Authorization header,
+// since browsers can't set headers on websockets. The subprotocol and cookie wsproxy reads take precedence. It also
+// keeps the address of the client, which the gateway forwards as x-forwarded-for.
+func websocketRequest(incoming *http.Request, outgoing *http.Request) *http.Request {
+	outgoing.RemoteAddr = incoming.RemoteAddr
+	query := outgoing.URL.Query()
+	accessToken := query.Get("access_token")
+	if accessToken == "" {
+		return outgoing
+	}
+	query.Del("access_token")
+	outgoing.URL.RawQuery = query.Encode()
+	if outgoing.Header.Get("Authorization") == "" {
+		outgoing.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return outgoing
+}
+
 func allowedOrigin(origin string) bool {
 	if viper.GetString("cors") == "*" {
 		return true
@@ -255,12 +426,35 @@ func allowedOrigin(origin string) bool {
 	return false
 }
 
+// archiveMIME is the type the gateway marshals the responses of archiveRequests with.
+const archiveMIME = "application/zip"
+
+// archiveMarshaler writes the HttpBody chunks of a streamed archive back to back. The default marshaler puts a
+// newline after every message of a stream, which would corrupt the archive. Errors are still written as json.
+type archiveMarshaler struct {
+	runtime.HTTPBodyMarshaler
+}
+
+func (m *archiveMarshaler) Delimiter() []byte {
+	return nil
+}
+
+// archiveRequest makes the gateway use archiveMarshaler for the endpoints that stream an archive.
+func archiveRequest(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api/v1/auth/profile/export" {
+			r.Header.Set("Accept", archiveMIME)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
 func cors(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if allowedOrigin(r.Header.Get("Origin")) {
 			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, ResponseType")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-Api-Key, ResponseType")
 		}
 		if r.Method == "OPTIONS" {
 			return
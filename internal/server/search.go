@@ -7,18 +7,22 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/facet"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/img2vec"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/od"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/productmeta"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/rank"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/resultcache"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/s3"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/search"
 	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/verification"
 	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"io"
 	"strconv"
+	"time"
 )
 
 type SearchServiceServer struct {
@@ -30,6 +34,8 @@ type SearchServiceServer struct {
 	fetcher        productmeta.Fetcher
 	s3Client       s3.Client
 	storage        *storage.Storage
+	resultCache    resultcache.Cache
+	verification   verification.Config
 }
 
 func NewSearchServiceServer(
@@ -40,6 +46,8 @@ func NewSearchServiceServer(
 	objectDetector od.ObjectDetector,
 	s3Client s3.Client,
 	store *storage.Storage,
+	resultCache resultcache.Cache,
+	verificationConfig verification.Config,
 ) *SearchServiceServer {
 	return &SearchServiceServer{
 		img2vec:        i2v,
@@ -49,6 +57,8 @@ func NewSearchServiceServer(
 		objectDetector: objectDetector,
 		s3Client:       s3Client,
 		storage:        store,
+		resultCache:    resultCache,
+		verification:   verificationConfig,
 	}
 }
 
@@ -59,73 +69,49 @@ func (s *SearchServiceServer) Search(ctx context.Context, req *pb.SearchRequest)
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	var history *storage.SearchHistory
-	user := GetContextUser(ctx)
-	if user != nil {
-		path := fmt.Sprintf("%s.jpg", uuid.New().String())
-		err := s.s3Client.Upload(ctx, "history-images", path, bytes.NewReader(req.Image), int64(len(req.Image)))
-		if err != nil {
-			logrus.Errorf("failed to upload image to s3: %v", err)
-		} else {
-			history = &storage.SearchHistory{
-				UserID:       user.ID,
-				QueryAddress: path,
-			}
-			err = s.storage.CreateSearchHistory(history)
-		}
-	}
+	history := s.createHistory(ctx, req.Image)
 	logrus.Debug("creating history done")
+	products, err := s.rankedProducts(ctx, req)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to upload the image: %v", err)
-	}
-	vector, err := s.img2vec.Vectorize(ctx, req.Image)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to vectorize the image: %v", err)
-	}
-	productImages, err := s.searchHandler.Search(ctx, vector, int(req.Params.TopK))
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to search: %v", err)
+		return nil, err
 	}
-	products := s.rankers[req.Params.Ranker].Rank(productImages)
 	logrus.Debug("ranking done")
 	respChan := s.fetcher.AsyncFetch(ctx, products, int(req.Params.TopK))
 	var resultProducts []*pb.Product
+	var failures []*pb.FetchFailure
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, status.Errorf(codes.Canceled, "client canceled the request")
 		case resp := <-respChan:
 			if resp == nil {
-				return &pb.SearchResponse{Products: resultProducts}, nil
+				return &pb.SearchResponse{
+					Products: resultProducts,
+					Failures: failures,
+					Facets:   facet.Compute(resultProducts),
+				}, nil
 			}
 			if resp.Product != nil {
 				resultProducts = append(resultProducts, resp.Product)
-				if history != nil && history.ID != 0 {
-					err := s.storage.CreateSearchHistoryResult(&storage.SearchHistoryResult{
-						SearchHistoryID: history.ID,
-						ProductID:       uint(resp.Product.Id),
-					})
-					if err != nil {
-						logrus.Errorf("failed to create search history result: %v", err)
-					}
-				}
+				s.addHistoryResult(history, resp.Product)
 			} else {
-				logrus.Error("error in fetching product: ", resp.Error)
+				logrus.WithField("reason", resp.Reason).Error("error in fetching product: ", resp.Error)
+				failures = append(failures, fetchFailure(resp))
 			}
 		}
 	}
 }
 
 func (s *SearchServiceServer) AsyncSearch(req *pb.SearchRequest, stream pb.SearchService_AsyncSearchServer) error {
-	vector, err := s.img2vec.Vectorize(stream.Context(), req.Image)
+	err := req.Validate()
 	if err != nil {
-		return status.Errorf(codes.Internal, "failed to vectorize the image: %v", err)
+		return status.Error(codes.InvalidArgument, err.Error())
 	}
-	productImages, err := s.searchHandler.Search(stream.Context(), vector, int(req.Params.TopK))
+	history := s.createHistory(stream.Context(), req.Image)
+	products, err := s.rankedProducts(stream.Context(), req)
 	if err != nil {
-		return status.Errorf(codes.Internal, "failed to search: %v", err)
+		return err
 	}
-	products := s.rankers[req.Params.Ranker].Rank(productImages)
 	respChan := s.fetcher.AsyncFetch(stream.Context(), products, int(req.Params.TopK))
 	for {
 		select {
@@ -136,6 +122,7 @@ func (s *SearchServiceServer) AsyncSearch(req *pb.SearchRequest, stream pb.Searc
 				return nil
 			}
 			if resp.Product != nil {
+				s.addHistoryResult(history, resp.Product)
 				if err := stream.Send(&pb.AsyncSearchResponse{Product: resp.Product}); err != nil {
 					return status.Errorf(codes.Internal, "failed to send product: %v", err)
 				}
@@ -144,6 +131,72 @@ func (s *SearchServiceServer) AsyncSearch(req *pb.SearchRequest, stream pb.Searc
 	}
 }
 
+// createHistory stores the query image and a search history entry for the calling user, if any and if history is
+// available to them. Failures are logged rather than failing the search, so it may return nil.
+func (s *SearchServiceServer) createHistory(ctx context.Context, image []byte) *storage.SearchHistory {
+	user := GetContextUser(ctx)
+	if user == nil || !s.verification.Allows(user, verification.FeatureHistory) {
+		return nil
+	}
+	path := fmt.Sprintf("%s.jpg", uuid.New().String())
+	err := s.s3Client.Upload(ctx, s3.HistoryImagesBucket, path, bytes.NewReader(image), int64(len(image)))
+	if err != nil {
+		logrus.Errorf("failed to upload image to s3: %v", err)
+		return nil
+	}
+	history := &storage.SearchHistory{
+		UserID:       user.ID,
+		QueryAddress: path,
+	}
+	if err := s.storage.CreateSearchHistory(history); err != nil {
+		logrus.Errorf("failed to create search history: %v", err)
+		return nil
+	}
+	return history
+}
+
+// addHistoryResult adds a fetched product to the results of a search history entry created by createHistory.
+func (s *SearchServiceServer) addHistoryResult(history *storage.SearchHistory, product *pb.Product) {
+	if history == nil || history.ID == 0 {
+		return
+	}
+	err := s.storage.CreateSearchHistoryResult(&storage.SearchHistoryResult{
+		SearchHistoryID: history.ID,
+		ProductID:       uint(product.Id),
+	})
+	if err != nil {
+		logrus.Errorf("failed to create search history result: %v", err)
+	}
+}
+
+func fetchFailure(resp *productmeta.ProductWithError) *pb.FetchFailure {
+	id, _ := strconv.Atoi(resp.ProductID)
+	return &pb.FetchFailure{ProductId: int32(id), Reason: resp.Reason}
+}
+
+// rankedProducts vectorizes the query image and returns the ranked products, consulting the result cache first.
+func (s *SearchServiceServer) rankedProducts(ctx context.Context, req *pb.SearchRequest) ([]rank.Product, error) {
+	vector, err := s.img2vec.Vectorize(ctx, req.Image)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to vectorize the image: %v", err)
+	}
+	key := resultcache.Key{
+		Vector: vector,
+		TopK:   int(req.Params.TopK),
+		Ranker: req.Params.Ranker.String(),
+	}
+	if products, ok := s.resultCache.Get(ctx, key); ok {
+		return products, nil
+	}
+	productImages, err := s.searchHandler.Search(ctx, vector, int(req.Params.TopK))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to search: %v", err)
+	}
+	products := s.rankers[req.Params.Ranker].Rank(productImages)
+	s.resultCache.Set(ctx, key, products)
+	return products, nil
+}
+
 func (s *SearchServiceServer) Crop(ctx context.Context, req *pb.CropRequest) (*pb.CropResponse, error) {
 	topLeft, bottomRight, err := s.objectDetector.Detect(ctx, req.Image)
 	if err != nil {
@@ -161,6 +214,121 @@ func (s *SearchServiceServer) Crop(ctx context.Context, req *pb.CropRequest) (*p
 	}, nil
 }
 
+func (s *SearchServiceServer) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.GetProductResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	product, err := s.fetcher.FetchDetails(ctx, int(req.Id))
+	if err != nil {
+		return nil, fetchStatus(err)
+	}
+	return &pb.GetProductResponse{Product: product}, nil
+}
+
+const (
+	defaultPriceHistoryDays = 90
+	maxPriceHistoryDays     = 365
+)
+
+// priceStatsWindows are the periods, in days, summarized by GetPriceHistory.
+var priceStatsWindows = []int{7, 30, 90}
+
+func (s *SearchServiceServer) GetPriceHistory(
+	ctx context.Context, req *pb.GetPriceHistoryRequest,
+) (*pb.GetPriceHistoryResponse, error) {
+	days := int(req.Days)
+	if days <= 0 {
+		days = defaultPriceHistoryDays
+	}
+	if days > maxPriceHistoryDays {
+		return nil, status.Errorf(codes.InvalidArgument, "days must not be greater than %d", maxPriceHistoryDays)
+	}
+	period := days
+	if last := priceStatsWindows[len(priceStatsWindows)-1]; last > period {
+		period = last
+	}
+	now := time.Now()
+	records, err := s.storage.GetPriceHistory(uint(req.ProductId), now.AddDate(0, 0, -period))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get price history: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, status.Errorf(codes.NotFound, "there is no price history")
+	}
+
+	seriesStart := now.AddDate(0, 0, -days)
+	points := make([]*pb.PricePoint, 0)
+	for i, record := range records {
+		if i+1 < len(records) && !records[i+1].RecordedAt.After(seriesStart) {
+			continue
+		}
+		at := record.RecordedAt
+		if at.Before(seriesStart) {
+			at = seriesStart
+		}
+		points = append(points, &pb.PricePoint{Time: at.Unix(), Price: record.Price})
+	}
+	stats := make([]*pb.PriceStats, 0)
+	for _, window := range priceStatsWindows {
+		if stat := priceStats(records, now.AddDate(0, 0, -window), now); stat != nil {
+			stat.Days = int32(window)
+			stats = append(stats, stat)
+		}
+	}
+	return &pb.GetPriceHistoryResponse{Points: points, Stats: stats}, nil
+}
+
+// priceStats summarizes the prices held between start and end, given records ordered by time. Each price holds
+// until the next record. It returns nil if no record covers the period.
+func priceStats(records []storage.PriceRecord, start time.Time, end time.Time) *pb.PriceStats {
+	var stat *pb.PriceStats
+	var weighted, total float64
+	for i, record := range records {
+		from, to := record.RecordedAt, end
+		if i+1 < len(records) {
+			to = records[i+1].RecordedAt
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if !to.After(start) || from.After(end) {
+			continue
+		}
+		if stat == nil {
+			stat = &pb.PriceStats{Min: record.Price, Max: record.Price, Avg: record.Price}
+		}
+		if record.Price < stat.Min {
+			stat.Min = record.Price
+		}
+		if record.Price > stat.Max {
+			stat.Max = record.Price
+		}
+		duration := to.Sub(from).Seconds()
+		weighted += float64(record.Price) * duration
+		total += duration
+	}
+	if stat != nil && total > 0 {
+		stat.Avg = int64(weighted / total)
+	}
+	return stat
+}
+
+// fetchStatus converts a product fetch error to a grpc status.
+func fetchStatus(err error) error {
+	switch productmeta.Reason(err) {
+	case productmeta.ReasonNotFound, productmeta.ReasonInactive:
+		return status.Errorf(codes.NotFound, "product not found")
+	case productmeta.ReasonThrottled:
+		return status.Errorf(codes.ResourceExhausted, "product catalog is busy, try again later")
+	case productmeta.ReasonUpstream, productmeta.ReasonRejected:
+		return status.Errorf(codes.Unavailable, "product catalog is unavailable")
+	case productmeta.ReasonCanceled:
+		return status.Errorf(codes.Canceled, "client canceled the request")
+	}
+	return status.Errorf(codes.Internal, "failed to fetch product: %v", err)
+}
+
 func (s *SearchServiceServer) GetSearchHistories(
 	ctx context.Context, req *pb.GetSearchHistoriesRequest,
 ) (*pb.GetSearchHistoriesResponse, error) {
@@ -179,7 +347,7 @@ func (s *SearchServiceServer) GetSearchHistories(
 		if len(history.Results) == 0 {
 			continue
 		}
-		imageReader, err := s.s3Client.Download(ctx, "history-images", history.QueryAddress)
+		imageReader, err := s.s3Client.Download(ctx, s3.HistoryImagesBucket, history.QueryAddress)
 		if err != nil {
 			logrus.Errorf("failed to download image: %v", err)
 			continue
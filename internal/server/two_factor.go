@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/token"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/twofactor"
+	pb "github.com/web-programming-fall-2022/digivision-backend/pkg/api/v1"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"strconv"
+	"time"
+)
+
+// EnrollTwoFactor generates an authenticator secret for the user, which is used once confirmed by
+// ConfirmTwoFactor.
+func (s *AuthServiceServer) EnrollTwoFactor(
+	ctx context.Context, req *pb.EnrollTwoFactorRequest,
+) (*pb.EnrollTwoFactorResponse, error) {
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	secret, uri, err := s.TwoFactor.Enroll(user)
+	if err != nil {
+		return nil, twoFactorError(err)
+	}
+	return &pb.EnrollTwoFactorResponse{
+		Secret:     secret,
+		OtpauthUri: uri,
+	}, nil
+}
+
+// ConfirmTwoFactor enables two factor authentication with a code of the enrolled secret and returns the recovery
+// codes of the user. Wrong codes count as failed logins.
+func (s *AuthServiceServer) ConfirmTwoFactor(
+	ctx context.Context, req *pb.ConfirmTwoFactorRequest,
+) (*pb.ConfirmTwoFactorResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	var recoveryCodes []string
+	err = s.guardAttempt(ctx, user, func() error {
+		recoveryCodes, err = s.TwoFactor.Confirm(user.ID, req.Code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ConfirmTwoFactorResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableTwoFactor turns two factor authentication off with a code and, for users with a password, the password.
+// Wrong codes and passwords count as failed logins.
+func (s *AuthServiceServer) DisableTwoFactor(
+	ctx context.Context, req *pb.DisableTwoFactorRequest,
+) (*emptypb.Empty, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	user := GetContextUser(ctx)
+	if user == nil {
+		return nil, errors.NotLoggedIn
+	}
+	err = s.guardAttempt(ctx, user, func() error {
+		if user.PasswordHash != "" {
+			err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+			if err != nil {
+				return errWrongPassword
+			}
+		}
+		return s.TwoFactor.Disable(user.ID, req.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// twoFactorChallenge returns the response of a login that needs a code of the second factor too.
+func (s *AuthServiceServer) twoFactorChallenge(user *storage.UserAccount, deviceName string) (*pb.LoginResponse, error) {
+	challenge, err := s.TokenManager.Generate(map[string]string{
+		token.ClaimUserID: strconv.Itoa(int(user.ID)),
+		token.ClaimType:   token.TypeTwoFactor,
+		token.ClaimDevice: deviceName,
+	}, s.TwoFactor.ChallengeExpiration())
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, status.Error(codes.Internal, "could not generate tokens")
+	}
+	return &pb.LoginResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+	}, nil
+}
+
+// VerifyTwoFactor finishes the login of a user with two factor authentication. Wrong codes count as failed logins.
+func (s *AuthServiceServer) VerifyTwoFactor(ctx context.Context, req *pb.VerifyTwoFactorRequest) (*pb.LoginResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	claims, err := s.TokenManager.ValidateType(ctx, req.ChallengeToken, token.TypeTwoFactor)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid challenge token")
+	}
+	userId, err := strconv.Atoi(claims[token.ClaimUserID])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "no userId in token")
+	}
+	user, err := s.Storage.GetUserByID(uint(userId))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid challenge token")
+	}
+	err = s.guardAttempt(ctx, user, func() error {
+		return s.TwoFactor.Verify(user.ID, req.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.AccountDisabled
+	}
+	// a challenge logs in once, it lives no longer than a challenge issued now
+	unused, err := s.TokenManager.UseOnce(ctx, claims[token.ClaimID], time.Until(s.TwoFactor.ChallengeExpiration()))
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, errors.Internal
+	}
+	if !unused {
+		return nil, status.Error(codes.Unauthenticated, "challenge token is already used")
+	}
+	authToken, refreshToken, err := s.generateTokens(ctx, user, claims[token.ClaimDevice])
+	if err != nil {
+		logrus.Errorln(err)
+		return nil, status.Error(codes.Internal, "could not generate tokens")
+	}
+	return &pb.LoginResponse{
+		AuthToken:    authToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// twoFactorError converts an error of the two factor manager to a grpc status.
+func twoFactorError(err error) error {
+	switch err {
+	case twofactor.ErrAlreadyEnabled, twofactor.ErrNotEnrolled, twofactor.ErrNotEnabled:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case twofactor.ErrWrongCode:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	logrus.Errorln(err)
+	return errors.Internal
+}
@@ -0,0 +1,86 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	BackendLog  = "log"
+	BackendHttp = "http"
+)
+
+// Sender sends text messages to phone numbers.
+type Sender interface {
+	Send(ctx context.Context, phoneNumber string, text string) error
+}
+
+// Config selects and configures the Sender. The log backend is meant for local development: it logs the messages
+// instead of sending them.
+type Config struct {
+	Backend string
+	Http    HttpConfig
+}
+
+// HttpConfig configures HttpSender. ApiKey, if set, is sent as a bearer token.
+type HttpConfig struct {
+	Url     string
+	ApiKey  string `mapstructure:"api_key" yaml:"api_key"`
+	Timeout int64
+}
+
+// NewSender returns the Sender of the backend selected by config.
+func NewSender(config Config) (Sender, error) {
+	switch config.Backend {
+	case BackendLog:
+		return LogSender{}, nil
+	case BackendHttp:
+		return NewHttpSender(config.Http), nil
+	}
+	return nil, fmt.Errorf("unknown sms backend %q", config.Backend)
+}
+
+// LogSender logs text messages instead of sending them.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, phoneNumber string, text string) error {
+	logrus.WithField("to", phoneNumber).Info("sms: ", text)
+	return nil
+}
+
+// HttpSender posts text messages as JSON ({"to": ..., "text": ...}) to an SMS gateway.
+type HttpSender struct {
+	url    string
+	apiKey string
+	client *resty.Client
+}
+
+func NewHttpSender(config HttpConfig) HttpSender {
+	return HttpSender{
+		url:    config.Url,
+		apiKey: config.ApiKey,
+		client: resty.New().SetTimeout(time.Duration(config.Timeout) * time.Second),
+	}
+}
+
+func (s HttpSender) Send(ctx context.Context, phoneNumber string, text string) error {
+	req := s.client.R().SetContext(ctx).SetBody(map[string]string{
+		"to":   phoneNumber,
+		"text": text,
+	})
+	if s.apiKey != "" {
+		req.SetAuthToken(s.apiKey)
+	}
+	resp, err := req.Post(s.url)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("sms gateway responded with %s", resp.Status())
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rbac"
+	"gorm.io/gorm"
+	"time"
+)
+
+// UserData is everything stored about a user, as exported to them.
+type UserData struct {
+	User            UserAccount
+	FavoriteLists   []FavoriteList
+	SearchHistories []SearchHistory
+	Sessions        []Session
+	Notifications   []Notification
+	Identities      []UserIdentity
+}
+
+// UpdateUserProfile stores the name, gender and phone number of a user, along with whether the phone number is
+// verified.
+func (storage *Storage) UpdateUserProfile(user *UserAccount) error {
+	if err := storage.DB.Model(user).
+		Select("first_name", "last_name", "gender", "phone_number", "phone_verified_at").
+		Updates(user).Error; err != nil {
+		return errors.New("couldn't update user in postgres storage")
+	}
+	return nil
+}
+
+// GetUserData returns everything stored about a user.
+func (storage *Storage) GetUserData(userID uint) (*UserData, error) {
+	data := UserData{}
+	storage.DB.First(&data.User, userID)
+	if data.User.ID == 0 {
+		return nil, errors.New("user not found")
+	}
+	queries := []struct {
+		query *gorm.DB
+		dest  interface{}
+	}{
+		{storage.DB.Preload("Items"), &data.FavoriteLists},
+		{storage.DB.Preload("Results"), &data.SearchHistories},
+		{storage.DB, &data.Sessions},
+		{storage.DB, &data.Notifications},
+		{storage.DB, &data.Identities},
+	}
+	for _, q := range queries {
+		if err := q.query.Where("user_id = ?", userID).Order("id").Find(q.dest).Error; err != nil {
+			return nil, errors.New("couldn't get user data from postgres storage")
+		}
+	}
+	return &data, nil
+}
+
+// GetSearchHistoryImages returns the paths of the query images of all search histories of a user, in the
+// history-images bucket.
+func (storage *Storage) GetSearchHistoryImages(userID uint) ([]string, error) {
+	paths := make([]string, 0)
+	if err := storage.DB.Unscoped().Model(&SearchHistory{}).Where("user_id = ? AND query_address <> ''", userID).
+		Pluck("query_address", &paths).Error; err != nil {
+		return nil, errors.New("couldn't get search histories from postgres storage")
+	}
+	return paths, nil
+}
+
+// DeleteUser hard deletes a user along with all rows that belong to them, children before their parents. Since the
+// sessions are deleted, all tokens of the user stop working.
+func (storage *Storage) DeleteUser(userID uint) error {
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteUser(tx, userID)
+	})
+	if err != nil {
+		return errors.New("couldn't delete user in postgres storage")
+	}
+	return nil
+}
+
+func deleteUser(tx *gorm.DB, userID uint) error {
+	histories := tx.Unscoped().Model(&SearchHistory{}).Select("id").Where("user_id = ?", userID)
+	if err := tx.Unscoped().Where("search_history_id IN (?)", histories).
+		Delete(&SearchHistoryResult{}).Error; err != nil {
+		return err
+	}
+	lists := tx.Unscoped().Model(&FavoriteList{}).Select("id").Where("user_id = ?", userID)
+	if err := tx.Unscoped().Where("favorite_list_id IN (?)", lists).
+		Delete(&FavoriteListItem{}).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&SearchHistory{},
+		&FavoriteList{},
+		&Notification{},
+		&Session{},
+		&PasswordResetToken{},
+		&VerificationCode{},
+		&UserIdentity{},
+		&RecoveryCode{},
+		&TwoFactor{},
+		&AuditEntry{},
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Delete(&UserAccount{}, userID).Error
+}
+
+// MergeGuest moves the search history, favorites and notifications of a guest to a user and deletes the guest.
+// Favorite lists are merged by name, skipping products the list of the user has already.
+func (storage *Storage) MergeGuest(guestID uint, userID uint) error {
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&SearchHistory{}, &Notification{}} {
+			if err := tx.Model(model).Where("user_id = ?", guestID).Update("user_id", userID).Error; err != nil {
+				return err
+			}
+		}
+		lists := make([]FavoriteList, 0)
+		if err := tx.Where("user_id = ?", guestID).Find(&lists).Error; err != nil {
+			return err
+		}
+		for _, list := range lists {
+			target := FavoriteList{}
+			tx.Where("user_id = ? AND name = ?", userID, list.Name).First(&target)
+			if target.ID == 0 {
+				if err := tx.Model(&list).Update("user_id", userID).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			existing := tx.Model(&FavoriteListItem{}).Select("product_id").Where("favorite_list_id = ?", target.ID)
+			if err := tx.Model(&FavoriteListItem{}).Where("favorite_list_id = ? AND product_id NOT IN (?)", list.ID, existing).
+				Update("favorite_list_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+		return deleteUser(tx, guestID)
+	})
+	if err != nil {
+		return errors.New("couldn't merge guest in postgres storage")
+	}
+	return nil
+}
+
+// GetInactiveGuests returns the ids of up to limit guests created before the given time who haven't used the app
+// since.
+func (storage *Storage) GetInactiveGuests(before time.Time, limit int) ([]uint, error) {
+	ids := make([]uint, 0)
+	activeSessions := storage.DB.Model(&Session{}).Select("1").
+		Where("sessions.user_id = user_accounts.id AND last_seen_at >= ?", before)
+	if err := storage.DB.Model(&UserAccount{}).Where("role = ? AND created_at < ?", rbac.RoleGuest, before).
+		Where("NOT EXISTS (?)", activeSessions).Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, errors.New("couldn't get guests from postgres storage")
+	}
+	return ids, nil
+}
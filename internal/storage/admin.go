@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// UserActivity summarizes what a user did, for staff.
+type UserActivity struct {
+	LastSeenAt     *time.Time
+	SearchCount    int
+	ActiveSessions int
+}
+
+// DailyUsage counts what happened on a day.
+type DailyUsage struct {
+	Day         time.Time
+	NewUsers    int
+	Logins      int
+	Searches    int
+	ActiveUsers int
+}
+
+// SearchUsers returns the users whose email, phone number or name contains query and who have the given role, if
+// any, newest first, along with the number of all matching users.
+func (storage *Storage) SearchUsers(query string, role string, offset int, limit int) ([]UserAccount, int64, error) {
+	db := storage.DB.Model(&UserAccount{})
+	if query != "" {
+		pattern := "%" + query + "%"
+		db = db.Where("email ILIKE ? OR phone_number ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?",
+			pattern, pattern, pattern, pattern)
+	}
+	if role != "" {
+		db = db.Where("role = ?", role)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("couldn't count users in postgres storage")
+	}
+	users := make([]UserAccount, 0)
+	if err := db.Order("id desc").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, errors.New("couldn't get users from postgres storage")
+	}
+	return users, total, nil
+}
+
+// GetUserActivity returns the activity of the given users by their id.
+func (storage *Storage) GetUserActivity(userIDs []uint) (map[uint]*UserActivity, error) {
+	activity := make(map[uint]*UserActivity, len(userIDs))
+	for _, id := range userIDs {
+		activity[id] = &UserActivity{}
+	}
+	if len(userIDs) == 0 {
+		return activity, nil
+	}
+	var sessions []struct {
+		UserID     uint
+		LastSeenAt time.Time
+		Active     int
+	}
+	if err := storage.DB.Model(&Session{}).
+		Select("user_id, MAX(last_seen_at) AS last_seen_at, "+
+			"COUNT(*) FILTER (WHERE revoked_at IS NULL AND expires_at > ?) AS active", time.Now()).
+		Where("user_id IN ?", userIDs).Group("user_id").Scan(&sessions).Error; err != nil {
+		return nil, errors.New("couldn't get sessions from postgres storage")
+	}
+	for _, s := range sessions {
+		lastSeenAt := s.LastSeenAt
+		activity[s.UserID].LastSeenAt = &lastSeenAt
+		activity[s.UserID].ActiveSessions = s.Active
+	}
+	var searches []struct {
+		UserID uint
+		Count  int
+	}
+	if err := storage.DB.Model(&SearchHistory{}).Select("user_id, COUNT(*) AS count").
+		Where("user_id IN ?", userIDs).Group("user_id").Scan(&searches).Error; err != nil {
+		return nil, errors.New("couldn't get search histories from postgres storage")
+	}
+	for _, s := range searches {
+		activity[s.UserID].SearchCount = s.Count
+	}
+	return activity, nil
+}
+
+// GetDailyUsage returns the usage of every day (UTC) since the given day, oldest first. Searches only count the
+// searches of logged in users, which are recorded.
+func (storage *Storage) GetDailyUsage(since time.Time) ([]DailyUsage, error) {
+	type dayCount struct {
+		Day   time.Time
+		Count int
+	}
+	count := func(model interface{}, expr string) (map[int64]int, error) {
+		var rows []dayCount
+		err := storage.DB.Model(model).
+			Select("date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, "+expr+" AS count").
+			Where("created_at >= ?", since).Group("day").Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		counts := make(map[int64]int, len(rows))
+		for _, r := range rows {
+			counts[r.Day.Unix()] = r.Count
+		}
+		return counts, nil
+	}
+	newUsers, err := count(&UserAccount{}, "COUNT(*)")
+	if err != nil {
+		return nil, errors.New("couldn't count users in postgres storage")
+	}
+	logins, err := count(&Session{}, "COUNT(*)")
+	if err != nil {
+		return nil, errors.New("couldn't count sessions in postgres storage")
+	}
+	searches, err := count(&SearchHistory{}, "COUNT(*)")
+	if err != nil {
+		return nil, errors.New("couldn't count search histories in postgres storage")
+	}
+	activeUsers, err := count(&SearchHistory{}, "COUNT(DISTINCT user_id)")
+	if err != nil {
+		return nil, errors.New("couldn't count search histories in postgres storage")
+	}
+	usage := make([]DailyUsage, 0)
+	for day := since.UTC().Truncate(24 * time.Hour); !day.After(time.Now()); day = day.AddDate(0, 0, 1) {
+		key := day.Unix()
+		usage = append(usage, DailyUsage{
+			Day:         day,
+			NewUsers:    newUsers[key],
+			Logins:      logins[key],
+			Searches:    searches[key],
+			ActiveUsers: activeUsers[key],
+		})
+	}
+	return usage, nil
+}
+
+func (storage *Storage) CountUsers() (int64, error) {
+	var count int64
+	if err := storage.DB.Model(&UserAccount{}).Count(&count).Error; err != nil {
+		return 0, errors.New("couldn't count users in postgres storage")
+	}
+	return count, nil
+}
+
+func (storage *Storage) CountActiveSessions() (int64, error) {
+	var count int64
+	if err := storage.DB.Model(&Session{}).Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Count(&count).Error; err != nil {
+		return 0, errors.New("couldn't count sessions in postgres storage")
+	}
+	return count, nil
+}
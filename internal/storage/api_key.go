@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// ApiKey lets a partner call the rpcs in Scopes without a user. Only the hash of the key is stored, Prefix is its
+// start to tell keys apart. RateLimit is the number of requests allowed per minute, unlimited if zero.
+type ApiKey struct {
+	gorm.Model
+	Name        string
+	Prefix      string
+	KeyHash     string   `gorm:"uniqueIndex"`
+	Scopes      []string `gorm:"serializer:json"`
+	RateLimit   int
+	ExpiresAt   *time.Time
+	RevokedAt   *time.Time
+	CreatedByID uint
+	LastUsedAt  *time.Time
+}
+
+// IsActive reports whether the key is neither revoked nor expired.
+func (k *ApiKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
+
+// Allows reports whether the key may call the rpc with the given full method name.
+func (k *ApiKey) Allows(method string) bool {
+	for _, scope := range k.Scopes {
+		if scope == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ApiKeyUsage counts the calls of an rpc with a key on a day (UTC).
+type ApiKeyUsage struct {
+	ApiKeyID uint      `gorm:"primaryKey;autoIncrement:false"`
+	Day      time.Time `gorm:"primaryKey;type:date"`
+	Method   string    `gorm:"primaryKey"`
+	Count    int64
+}
+
+func (storage *Storage) CreateApiKey(key *ApiKey) error {
+	if err := storage.DB.Create(key).Error; err != nil {
+		return errors.New("couldn't create api key in postgres storage")
+	}
+	return nil
+}
+
+// ErrApiKeyNotFound is returned by GetApiKeyByHash if there is no key with the hash, as opposed to failing to look
+// it up.
+var ErrApiKeyNotFound = errors.New("api key not found")
+
+func (storage *Storage) GetApiKeyByHash(keyHash string) (*ApiKey, error) {
+	key := ApiKey{}
+	if err := storage.DB.Where("key_hash = ?", keyHash).Limit(1).Find(&key).Error; err != nil {
+		return nil, errors.New("couldn't get api key from postgres storage")
+	}
+	if key.ID == 0 {
+		return nil, ErrApiKeyNotFound
+	}
+	return &key, nil
+}
+
+func (storage *Storage) GetApiKeyByID(id uint) (*ApiKey, error) {
+	key := ApiKey{}
+	storage.DB.First(&key, id)
+	if key.ID == 0 {
+		return nil, errors.New("api key not found")
+	}
+	return &key, nil
+}
+
+// GetApiKeys returns all keys, newest first.
+func (storage *Storage) GetApiKeys() ([]ApiKey, error) {
+	keys := make([]ApiKey, 0)
+	if err := storage.DB.Order("id desc").Find(&keys).Error; err != nil {
+		return nil, errors.New("couldn't get api keys from postgres storage")
+	}
+	return keys, nil
+}
+
+func (storage *Storage) RevokeApiKey(id uint) error {
+	if err := storage.DB.Model(&ApiKey{}).Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't revoke api key in postgres storage")
+	}
+	return nil
+}
+
+// AddApiKeyUsage adds counted calls to the usage of keys and records that the keys were last used at usedAt.
+func (storage *Storage) AddApiKeyUsage(usage []ApiKeyUsage, usedAt time.Time) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(usage))
+	for _, u := range usage {
+		ids = append(ids, u.ApiKeyID)
+	}
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "api_key_id"}, {Name: "day"}, {Name: "method"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("api_key_usages.count + excluded.count")}),
+		}).Create(&usage).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ApiKey{}).Where("id IN ?", ids).Update("last_used_at", usedAt).Error
+	})
+	if err != nil {
+		return errors.New("couldn't record api key usage in postgres storage")
+	}
+	return nil
+}
+
+// GetApiKeyUsage returns the usage of a key since the given day, oldest first.
+func (storage *Storage) GetApiKeyUsage(id uint, since time.Time) ([]ApiKeyUsage, error) {
+	usage := make([]ApiKeyUsage, 0)
+	if err := storage.DB.Where("api_key_id = ? AND day >= ?", id, since.UTC().Truncate(24*time.Hour)).
+		Order("day, method").Find(&usage).Error; err != nil {
+		return nil, errors.New("couldn't get api key usage from postgres storage")
+	}
+	return usage, nil
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+)
+
+// AuditEntry records a security relevant event, e.g. a lockout, for later investigation. UserID is zero if the
+// event concerns no known user.
+type AuditEntry struct {
+	gorm.Model
+	Action  string `gorm:"index"`
+	UserID  uint   `gorm:"index"`
+	Subject string
+	IP      string
+	Details map[string]interface{} `gorm:"serializer:json"`
+}
+
+func (storage *Storage) CreateAuditEntry(entry *AuditEntry) error {
+	if err := storage.DB.Create(entry).Error; err != nil {
+		return errors.New("couldn't create audit entry in postgres storage")
+	}
+	return nil
+}
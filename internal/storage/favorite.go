@@ -3,6 +3,7 @@ package storage
 import (
 	"errors"
 	"gorm.io/gorm"
+	"time"
 )
 
 type FavoriteList struct {
@@ -17,7 +18,16 @@ type FavoriteListItem struct {
 	gorm.Model
 	FavoriteListID uint
 	FavoriteList   FavoriteList `gorm:"ONDELETE:CASCADE"`
-	ProductID      uint
+	ProductID      uint         `gorm:"index"`
+	// PriceDropPercent is the minimum drop, relative to BaselinePrice, the user is alerted about. Zero disables
+	// price drop alerts.
+	PriceDropPercent  int32 `gorm:"default:10"`
+	NotifyBackInStock bool  `gorm:"default:true"`
+	// BaselinePrice and Available are the product state alerts are computed against. BaselinePrice is the highest
+	// price since the last price drop alert. CheckedAt is nil until the product is checked for the first time.
+	BaselinePrice int64
+	Available     bool
+	CheckedAt     *time.Time
 }
 
 func (storage *Storage) CreateFavoriteList(list *FavoriteList) error {
@@ -80,3 +90,48 @@ func (storage *Storage) RemoveItemFromList(listID uint, productID uint) error {
 	}
 	return nil
 }
+
+// GetFavoriteProductIDs returns up to limit distinct favorited product ids greater than afterID, in ascending order.
+func (storage *Storage) GetFavoriteProductIDs(afterID uint, limit int) ([]uint, error) {
+	ids := make([]uint, 0)
+	if err := storage.DB.Model(&FavoriteListItem{}).Distinct("product_id").
+		Where("product_id > ?", afterID).Order("product_id").Limit(limit).Pluck("product_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetFavoriteItemsByProductID returns the favorite list items of a product along with their lists and owners.
+func (storage *Storage) GetFavoriteItemsByProductID(productID uint) ([]FavoriteListItem, error) {
+	items := make([]FavoriteListItem, 0)
+	if err := storage.DB.Where("product_id = ?", productID).
+		Preload("FavoriteList.User").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// UpdateFavoriteItemAlertState stores the product state the alerts of item are computed against.
+func (storage *Storage) UpdateFavoriteItemAlertState(item *FavoriteListItem) error {
+	return storage.DB.Model(item).
+		Select("baseline_price", "available", "checked_at").
+		Updates(item).Error
+}
+
+func (storage *Storage) UpdateFavoriteItemAlert(
+	listID uint, productID uint, priceDropPercent int32, notifyBackInStock bool,
+) error {
+	result := storage.DB.Model(&FavoriteListItem{}).
+		Where("favorite_list_id = ? AND product_id = ?", listID, productID).
+		Updates(map[string]interface{}{
+			"price_drop_percent":   priceDropPercent,
+			"notify_back_in_stock": notifyBackInStock,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("item not found")
+	}
+	return nil
+}
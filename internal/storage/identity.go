@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links an account of an external OpenID Connect provider, identified by its subject, to a user.
+type UserIdentity struct {
+	gorm.Model
+	UserID   uint        `gorm:"index"`
+	User     UserAccount `gorm:"ONDELETE:CASCADE"`
+	Provider string      `gorm:"uniqueIndex:idx_identity_provider_subject"`
+	Subject  string      `gorm:"uniqueIndex:idx_identity_provider_subject"`
+	Email    string
+}
+
+func (storage *Storage) CreateUserIdentity(identity *UserIdentity) error {
+	if err := storage.DB.Create(identity).Error; err != nil {
+		return errors.New("couldn't create user identity in postgres storage")
+	}
+	return nil
+}
+
+// GetUserIdentity returns the identity of a provider account along with its user.
+func (storage *Storage) GetUserIdentity(provider string, subject string) (*UserIdentity, error) {
+	identity := UserIdentity{}
+	storage.DB.Preload("User").Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if identity.ID == 0 {
+		return nil, errors.New("user identity not found")
+	}
+	return &identity, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	NotificationPriceDrop   = "price_drop"
+	NotificationBackInStock = "back_in_stock"
+)
+
+// Notification is an entry of a user's in-app inbox.
+type Notification struct {
+	gorm.Model
+	UserID    uint        `gorm:"index"`
+	User      UserAccount `gorm:"ONDELETE:CASCADE"`
+	Kind      string
+	ProductID uint
+	Title     string
+	Body      string
+	OldPrice  int64
+	NewPrice  int64
+	ReadAt    *time.Time
+}
+
+func (storage *Storage) CreateNotification(notification *Notification) error {
+	if err := storage.DB.Create(notification).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetNotificationsByUserID returns the notifications of a user, newest first, and the number of unread ones.
+func (storage *Storage) GetNotificationsByUserID(
+	userID uint, offset int, limit int, unreadOnly bool,
+) ([]Notification, int64, error) {
+	notifications := make([]Notification, 0)
+	query := storage.DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Order("id desc").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	var unread int64
+	if err := storage.DB.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, unread, nil
+}
+
+// MarkNotificationsRead marks the given notifications of a user as read, or all of them if ids is empty.
+func (storage *Storage) MarkNotificationsRead(userID uint, ids []uint) error {
+	query := storage.DB.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) != 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	return query.Update("read_at", time.Now()).Error
+}
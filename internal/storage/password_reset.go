@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// PasswordResetToken is a single use token to set a new password. Only the hash of the token is stored.
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint
+	User      UserAccount `gorm:"ONDELETE:CASCADE"`
+	TokenHash string      `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (storage *Storage) CreatePasswordResetToken(token *PasswordResetToken) error {
+	if err := storage.DB.Create(token).Error; err != nil {
+		return errors.New("couldn't create password reset token in postgres storage")
+	}
+	return nil
+}
+
+// UsePasswordResetToken marks the unused, unexpired token with the given hash as used, along with all other tokens
+// of its user, and returns it.
+func (storage *Storage) UsePasswordResetToken(tokenHash string) (*PasswordResetToken, error) {
+	token := PasswordResetToken{}
+	storage.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).First(&token)
+	if token.ID == 0 {
+		return nil, errors.New("password reset token not found")
+	}
+	now := time.Now()
+	result := storage.DB.Model(&PasswordResetToken{}).Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, errors.New("couldn't use password reset token in postgres storage")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("password reset token not found")
+	}
+	if err := storage.DB.Model(&PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", token.UserID).
+		Update("used_at", now).Error; err != nil {
+		return nil, errors.New("couldn't use password reset token in postgres storage")
+	}
+	return &token, nil
+}
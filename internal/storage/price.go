@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PriceRecord is the price of a product at a point in time. A record is kept whenever the price changes and at
+// most once per minimum interval otherwise, so the price holds until the next record.
+type PriceRecord struct {
+	gorm.Model
+	ProductID  uint `gorm:"index:idx_price_record_product_time"`
+	Price      int64
+	RecordedAt time.Time `gorm:"index:idx_price_record_product_time"`
+}
+
+// priceRecordLock is the class of the postgres advisory locks taken per product while recording prices.
+const priceRecordLock = 7243002
+
+// RecordPrices stores the current prices of products, keyed by product id, skipping each one whose last record has
+// the same price and is younger than minInterval. The products are locked while checking, so concurrent calls
+// don't store the same price twice.
+func (storage *Storage) RecordPrices(prices map[uint]int64, minInterval time.Duration) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	now := time.Now()
+	// products are locked in order, so two calls can't wait for each other's locks
+	productIDs := make([]int64, 0, len(prices))
+	for productID := range prices {
+		productIDs = append(productIDs, int64(productID))
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+	locks := make([]string, 0, len(prices))
+	lockArgs := []interface{}{priceRecordLock}
+	values := make([]string, 0, len(prices))
+	args := []interface{}{now, now, now}
+	for _, productID := range productIDs {
+		locks = append(locks, "?::bigint")
+		lockArgs = append(lockArgs, productID)
+		values = append(values, "(?::bigint, ?::bigint)")
+		args = append(args, productID, prices[uint(productID)])
+	}
+	args = append(args, now.Add(-minInterval))
+	query := `INSERT INTO price_records (product_id, price, created_at, updated_at, recorded_at)
+SELECT v.product_id, v.price, ?::timestamptz, ?::timestamptz, ?::timestamptz FROM (VALUES ` + strings.Join(values, ", ") + `) AS v (product_id, price)
+WHERE NOT EXISTS (
+	SELECT 1 FROM price_records p
+	WHERE p.product_id = v.product_id AND p.deleted_at IS NULL AND p.price = v.price AND p.recorded_at > ?
+	AND NOT EXISTS (
+		SELECT 1 FROM price_records q
+		WHERE q.product_id = p.product_id AND q.deleted_at IS NULL AND q.recorded_at > p.recorded_at
+	)
+)`
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		// the locks are held until the commit, so the next call sees the records of this one
+		lock := "SELECT pg_advisory_xact_lock(?, (id % 2147483648)::int) FROM unnest(ARRAY[" + strings.Join(locks, ", ") +
+			"]) AS id"
+		if err := tx.Exec(lock, lockArgs...).Error; err != nil {
+			return err
+		}
+		return tx.Exec(query, args...).Error
+	})
+	if err != nil {
+		return errors.New("couldn't create price records in postgres storage")
+	}
+	return nil
+}
+
+// GetPriceHistory returns the price records of a product since the given time, oldest first, preceded by the last
+// record before it (if any) which holds the price at the start of the period.
+func (storage *Storage) GetPriceHistory(productID uint, since time.Time) ([]PriceRecord, error) {
+	baseline := PriceRecord{}
+	if err := storage.DB.Where("product_id = ? AND recorded_at < ?", productID, since).
+		Order("recorded_at desc").Limit(1).Find(&baseline).Error; err != nil {
+		return nil, errors.New("couldn't get price history from postgres storage")
+	}
+	records := make([]PriceRecord, 0)
+	if err := storage.DB.Where("product_id = ? AND recorded_at >= ?", productID, since).
+		Order("recorded_at").Find(&records).Error; err != nil {
+		return nil, errors.New("couldn't get price history from postgres storage")
+	}
+	if baseline.ID != 0 {
+		records = append([]PriceRecord{baseline}, records...)
+	}
+	return records, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Product is a catalog entry. Its ID is the product id of the catalog it was imported from.
+type Product struct {
+	gorm.Model
+	Title      string
+	Url        string
+	Status     string
+	ImageUrl   string
+	Rate       int32
+	RateCount  int32
+	Price      int64
+	IsInactive bool
+	Categories []ProductCategory `gorm:"serializer:json"`
+	Brand      string
+	Images     []string `gorm:"serializer:json"`
+	// RrpPrice is the recommended retail price of the default variant, DiscountPercent its discount relative to it.
+	RrpPrice        int64
+	DiscountPercent int32
+	SellerCount     int32
+	Variants        []ProductVariant `gorm:"serializer:json"`
+	Specs           []ProductSpec    `gorm:"serializer:json"`
+	// SyncedAt is the last time the product was fetched from the upstream catalog.
+	SyncedAt time.Time `gorm:"index"`
+}
+
+type ProductCategory struct {
+	Title string `json:"title"`
+	Url   string `json:"url"`
+}
+
+type ProductVariant struct {
+	ID              int64  `json:"id"`
+	Color           string `json:"color"`
+	ColorHex        string `json:"color_hex"`
+	Size            string `json:"size"`
+	Price           int64  `json:"price"`
+	RrpPrice        int64  `json:"rrp_price"`
+	DiscountPercent int32  `json:"discount_percent"`
+	InStock         bool   `json:"in_stock"`
+	Stock           int32  `json:"stock"`
+}
+
+// ProductSpec is a key specification of a product, e.g. "RAM" with values ["8 GB"].
+type ProductSpec struct {
+	Title  string   `json:"title"`
+	Values []string `json:"values"`
+}
+
+func (storage *Storage) GetProductByID(id uint) (*Product, error) {
+	product := Product{}
+	storage.DB.First(&product, id)
+	if product.ID == 0 {
+		return nil, errors.New("product not found")
+	}
+	return &product, nil
+}
+
+func (storage *Storage) UpsertProduct(product *Product) error {
+	if err := storage.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(product).Error; err != nil {
+		return errors.New("couldn't upsert product in postgres storage")
+	}
+	return nil
+}
+
+// DeleteProduct removes a product, e.g. one the upstream catalog doesn't have anymore.
+func (storage *Storage) DeleteProduct(id uint) error {
+	if err := storage.DB.Unscoped().Delete(&Product{}, id).Error; err != nil {
+		return errors.New("couldn't delete product in postgres storage")
+	}
+	return nil
+}
+
+// GetStaleProducts returns up to limit active products, oldest first, that have not been synced since the given
+// time. Inactive products are left to be refreshed when they're fetched, so they can't take up every batch.
+func (storage *Storage) GetStaleProducts(syncedBefore time.Time, limit int) ([]Product, error) {
+	products := make([]Product, 0)
+	if err := storage.DB.Where("synced_at < ? AND NOT is_inactive", syncedBefore).
+		Order("synced_at").Limit(limit).Find(&products).Error; err != nil {
+		return nil, errors.New("couldn't get stale products from postgres storage")
+	}
+	return products, nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Session is a login of a user on a device. All tokens issued for the login carry the session id, so revoking the
+// session revokes them. Refresh tokens are single use: every refresh replaces CurrentTokenID, so presenting an
+// older refresh token of the session means it was stolen.
+type Session struct {
+	gorm.Model
+	UserID         uint        `gorm:"index"`
+	User           UserAccount `gorm:"ONDELETE:CASCADE"`
+	DeviceName     string
+	UserAgent      string
+	IP             string
+	LastSeenAt     time.Time
+	CurrentTokenID string
+	ExpiresAt      time.Time
+	RevokedAt      *time.Time
+}
+
+// IsActive reports whether the session is neither revoked nor expired.
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
+
+func (storage *Storage) CreateSession(session *Session) error {
+	if err := storage.DB.Create(session).Error; err != nil {
+		return errors.New("couldn't create session in postgres storage")
+	}
+	return nil
+}
+
+// GetSessionByID returns a session along with its user.
+func (storage *Storage) GetSessionByID(id uint) (*Session, error) {
+	session := Session{}
+	storage.DB.Preload("User").First(&session, id)
+	if session.ID == 0 {
+		return nil, errors.New("session not found")
+	}
+	return &session, nil
+}
+
+// GetActiveSessionsByUserID returns the sessions of a user that are neither revoked nor expired, most recently
+// seen first.
+func (storage *Storage) GetActiveSessionsByUserID(userID uint) ([]Session, error) {
+	sessions := make([]Session, 0)
+	if err := storage.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").Find(&sessions).Error; err != nil {
+		return nil, errors.New("couldn't get sessions from postgres storage")
+	}
+	return sessions, nil
+}
+
+// TouchSession records that the session was used now from the given ip.
+func (storage *Storage) TouchSession(id uint, ip string) error {
+	if err := storage.DB.Model(&Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_seen_at": time.Now(),
+		"ip":           ip,
+	}).Error; err != nil {
+		return errors.New("couldn't update session in postgres storage")
+	}
+	return nil
+}
+
+// RotateSessionToken replaces the current refresh token of an active session if it is tokenID. It reports whether
+// the token was current.
+func (storage *Storage) RotateSessionToken(id uint, tokenID string, newTokenID string, expiresAt time.Time) (bool, error) {
+	result := storage.DB.Model(&Session{}).
+		Where("id = ? AND current_token_id = ? AND revoked_at IS NULL AND expires_at > ?", id, tokenID, time.Now()).
+		Updates(map[string]interface{}{
+			"current_token_id": newTokenID,
+			"expires_at":       expiresAt,
+			"last_seen_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return false, errors.New("couldn't rotate session token in postgres storage")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeSession revokes a session of a user. It returns an error if the user has no such active session.
+func (storage *Storage) RevokeSession(userID uint, id uint) error {
+	result := storage.DB.Model(&Session{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.New("couldn't revoke session in postgres storage")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes all sessions of a user except the given one.
+func (storage *Storage) RevokeOtherSessions(userID uint, exceptID uint) error {
+	if err := storage.DB.Model(&Session{}).Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't revoke sessions in postgres storage")
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes all sessions of a user.
+func (storage *Storage) RevokeAllSessions(userID uint) error {
+	if err := storage.DB.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't revoke sessions in postgres storage")
+	}
+	return nil
+}
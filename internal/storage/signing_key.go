@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// signingKeyLock is the postgres advisory lock taken while rotating signing keys.
+const signingKeyLock = 7243001
+
+// SigningKey is a key tokens are signed with, identified by Kid. The newest key whose ActivatesAt has passed signs
+// new tokens; every key verifies tokens until its ExpiresAt, which is unset until a newer key replaces it. PrivateKey
+// is encrypted by the token package.
+type SigningKey struct {
+	gorm.Model
+	Kid         string `gorm:"uniqueIndex"`
+	Alg         string
+	PrivateKey  string
+	ActivatesAt time.Time
+	ExpiresAt   *time.Time
+}
+
+// GetSigningKeys returns the unexpired keys, oldest first.
+func (storage *Storage) GetSigningKeys() ([]SigningKey, error) {
+	var keys []SigningKey
+	err := storage.DB.Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("activates_at").Find(&keys).Error
+	if err != nil {
+		return nil, errors.New("couldn't get signing keys from postgres storage")
+	}
+	return keys, nil
+}
+
+// AddSigningKey adds a key replacing the newest one, latestID, which expires at latestExpiresAt. It reports false
+// without adding the key if latestID is not the newest key anymore, i.e. another instance rotated the keys first.
+// latestID is zero for the first key.
+func (storage *Storage) AddSigningKey(key *SigningKey, latestID uint, latestExpiresAt time.Time) (bool, error) {
+	added := false
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyLock).Error; err != nil {
+			return err
+		}
+		latest := SigningKey{}
+		tx.Order("activates_at desc").First(&latest)
+		if latest.ID != latestID {
+			return nil
+		}
+		if latest.ID != 0 {
+			if err := tx.Model(&latest).Update("expires_at", latestExpiresAt).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		added = true
+		return nil
+	})
+	if err != nil {
+		return false, errors.New("couldn't add signing key in postgres storage")
+	}
+	return added, nil
+}
@@ -43,11 +43,51 @@ func NewStorage(masterConfig *DBConfig, replicaConfigs ...*DBConfig) *Storage {
 }
 
 func (storage *Storage) Migrate() error {
+	// the email and phone number indexes used to cover empty values too, which phone only users, users of social
+	// logins and guests have
+	for _, index := range []string{"idx_email", "idx_phone_number"} {
+		if storage.DB.Migrator().HasIndex(&UserAccount{}, index) {
+			if err := storage.DB.Migrator().DropIndex(&UserAccount{}, index); err != nil {
+				return errors.Wrap(err, "failed to drop "+index)
+			}
+		}
+	}
 	if err := storage.DB.AutoMigrate(&UserAccount{}); err != nil {
 		return errors.Wrap(err, "failed to migrate UserAccount")
 	}
-	if err := storage.DB.AutoMigrate(&UnauthorizedToken{}); err != nil {
-		return errors.Wrap(err, "failed to migrate UnauthorizedToken")
+	if err := storage.DB.AutoMigrate(&SigningKey{}); err != nil {
+		return errors.Wrap(err, "failed to migrate SigningKey")
+	}
+	// tokens used to be revoked by listing them, sessions are revoked instead
+	if err := storage.DB.Migrator().DropTable("unauthorized_tokens"); err != nil {
+		return errors.Wrap(err, "failed to drop unauthorized_tokens")
+	}
+	if err := storage.DB.AutoMigrate(&Session{}); err != nil {
+		return errors.Wrap(err, "failed to migrate Session")
+	}
+	if err := storage.DB.AutoMigrate(&PasswordResetToken{}); err != nil {
+		return errors.Wrap(err, "failed to migrate PasswordResetToken")
+	}
+	if err := storage.DB.AutoMigrate(&AuditEntry{}); err != nil {
+		return errors.Wrap(err, "failed to migrate AuditEntry")
+	}
+	if err := storage.DB.AutoMigrate(&ApiKey{}); err != nil {
+		return errors.Wrap(err, "failed to migrate ApiKey")
+	}
+	if err := storage.DB.AutoMigrate(&ApiKeyUsage{}); err != nil {
+		return errors.Wrap(err, "failed to migrate ApiKeyUsage")
+	}
+	if err := storage.DB.AutoMigrate(&UserIdentity{}); err != nil {
+		return errors.Wrap(err, "failed to migrate UserIdentity")
+	}
+	if err := storage.DB.AutoMigrate(&VerificationCode{}); err != nil {
+		return errors.Wrap(err, "failed to migrate VerificationCode")
+	}
+	if err := storage.DB.AutoMigrate(&TwoFactor{}); err != nil {
+		return errors.Wrap(err, "failed to migrate TwoFactor")
+	}
+	if err := storage.DB.AutoMigrate(&RecoveryCode{}); err != nil {
+		return errors.Wrap(err, "failed to migrate RecoveryCode")
 	}
 	if err := storage.DB.AutoMigrate(&FavoriteList{}); err != nil {
 		return errors.Wrap(err, "failed to migrate FavoriteList")
@@ -61,6 +101,15 @@ func (storage *Storage) Migrate() error {
 	if err := storage.DB.AutoMigrate(&SearchHistoryResult{}); err != nil {
 		return errors.Wrap(err, "failed to migrate SearchHistoryResult")
 	}
+	if err := storage.DB.AutoMigrate(&Product{}); err != nil {
+		return errors.Wrap(err, "failed to migrate Product")
+	}
+	if err := storage.DB.AutoMigrate(&PriceRecord{}); err != nil {
+		return errors.Wrap(err, "failed to migrate PriceRecord")
+	}
+	if err := storage.DB.AutoMigrate(&Notification{}); err != nil {
+		return errors.Wrap(err, "failed to migrate Notification")
+	}
 	return nil
 }
 
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// TwoFactor is the authenticator of a user. The secret is encrypted. ConfirmedAt is nil until the user enters a
+// first code, and LastUsedStep is the time step of the last code used, so a code can't be used twice.
+type TwoFactor struct {
+	gorm.Model
+	UserID          uint        `gorm:"uniqueIndex"`
+	User            UserAccount `gorm:"ONDELETE:CASCADE"`
+	SecretEncrypted string
+	ConfirmedAt     *time.Time
+	LastUsedStep    int64
+}
+
+// RecoveryCode is a single use code to log in without the authenticator. Only the hash of the code is stored.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint        `gorm:"index"`
+	User     UserAccount `gorm:"ONDELETE:CASCADE"`
+	CodeHash string
+	UsedAt   *time.Time
+}
+
+func (storage *Storage) GetTwoFactor(userID uint) (*TwoFactor, error) {
+	twoFactor := TwoFactor{}
+	storage.DB.Where("user_id = ?", userID).First(&twoFactor)
+	if twoFactor.ID == 0 {
+		return nil, errors.New("two factor not found")
+	}
+	return &twoFactor, nil
+}
+
+// CreateTwoFactor stores a new, unconfirmed authenticator of a user, replacing an unconfirmed one.
+func (storage *Storage) CreateTwoFactor(twoFactor *TwoFactor) error {
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ? AND confirmed_at IS NULL", twoFactor.UserID).
+			Delete(&TwoFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(twoFactor).Error
+	})
+	if err != nil {
+		return errors.New("couldn't create two factor in postgres storage")
+	}
+	return nil
+}
+
+// ConfirmTwoFactor enables the authenticator of a user once a code of the given step is entered, and replaces their
+// recovery codes.
+func (storage *Storage) ConfirmTwoFactor(userID uint, step int64, codeHashes []string) error {
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&TwoFactor{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"confirmed_at":   time.Now(),
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		return errors.New("couldn't confirm two factor in postgres storage")
+	}
+	return nil
+}
+
+// UseTwoFactorStep records that a code of the given step was used. It reports false if a code of the step, or a
+// later one, was used already.
+func (storage *Storage) UseTwoFactorStep(userID uint, step int64) (bool, error) {
+	result := storage.DB.Model(&TwoFactor{}).Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, errors.New("couldn't update two factor in postgres storage")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UseRecoveryCode marks the unused recovery code of a user with the given hash as used. It reports false if there
+// is none.
+func (storage *Storage) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := storage.DB.Model(&RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, errors.New("couldn't use recovery code in postgres storage")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteTwoFactor removes the authenticator and recovery codes of a user.
+func (storage *Storage) DeleteTwoFactor(userID uint) error {
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&TwoFactor{}).Error
+	})
+	if err != nil {
+		return errors.New("couldn't delete two factor in postgres storage")
+	}
+	return nil
+}
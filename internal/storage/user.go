@@ -2,17 +2,38 @@ package storage
 
 import (
 	"errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/rbac"
 	"gorm.io/gorm"
+	"time"
 )
 
 type UserAccount struct {
 	gorm.Model
-	Email        string `gorm:"uniqueIndex:idx_email"`
-	PhoneNumber  string `gorm:"uniqueIndex:idx_phone_number"`
+	// Email is empty for users who signed up with a phone number only, the phone number for users who signed up with
+	// a social login, and both for guests.
+	Email        string `gorm:"uniqueIndex:idx_user_email,where:email <> ''"`
+	PhoneNumber  string `gorm:"uniqueIndex:idx_user_phone_number,where:phone_number <> ''"`
 	Gender       string `gorm:"type:VARCHAR(1)"`
 	FirstName    string
 	LastName     string
 	PasswordHash string
+	// EmailVerifiedAt and PhoneVerifiedAt are nil until the email address and phone number are verified.
+	EmailVerifiedAt *time.Time
+	PhoneVerifiedAt *time.Time
+	Role            string `gorm:"default:user"`
+	// DisabledAt is set while staff have disabled the account, which can't log in then.
+	DisabledAt     *time.Time
+	DisabledReason string
+}
+
+// IsVerified reports whether the user has verified their email address or phone number.
+func (u *UserAccount) IsVerified() bool {
+	return u.EmailVerifiedAt != nil || u.PhoneVerifiedAt != nil
+}
+
+// IsGuest reports whether the user is a guest without an account, see rbac.RoleGuest.
+func (u *UserAccount) IsGuest() bool {
+	return u.Role == rbac.RoleGuest
 }
 
 func (storage *Storage) CreateUser(user *UserAccount) error {
@@ -32,6 +53,9 @@ func (storage *Storage) GetUserByID(id uint) (*UserAccount, error) {
 }
 
 func (storage *Storage) GetUserByEmail(email string) (*UserAccount, error) {
+	if email == "" {
+		return nil, errors.New("user not found")
+	}
 	user := UserAccount{Email: email}
 	storage.DB.Where(&user).First(&user)
 	if user.ID == 0 {
@@ -41,6 +65,9 @@ func (storage *Storage) GetUserByEmail(email string) (*UserAccount, error) {
 }
 
 func (storage *Storage) GetUserByPhoneNumber(phoneNumber string) (*UserAccount, error) {
+	if phoneNumber == "" {
+		return nil, errors.New("user not found")
+	}
 	user := UserAccount{PhoneNumber: phoneNumber}
 	storage.DB.Where(&user).First(&user)
 	if user.ID == 0 {
@@ -48,3 +75,50 @@ func (storage *Storage) GetUserByPhoneNumber(phoneNumber string) (*UserAccount,
 	}
 	return &user, nil
 }
+
+func (storage *Storage) UpdateUserPassword(id uint, passwordHash string) error {
+	if err := storage.DB.Model(&UserAccount{}).Where("id = ?", id).
+		Update("password_hash", passwordHash).Error; err != nil {
+		return errors.New("couldn't update user password in postgres storage")
+	}
+	return nil
+}
+
+func (storage *Storage) SetEmailVerified(id uint) error {
+	if err := storage.DB.Model(&UserAccount{}).Where("id = ?", id).
+		Update("email_verified_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't update user in postgres storage")
+	}
+	return nil
+}
+
+func (storage *Storage) SetPhoneVerified(id uint) error {
+	if err := storage.DB.Model(&UserAccount{}).Where("id = ?", id).
+		Update("phone_verified_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't update user in postgres storage")
+	}
+	return nil
+}
+
+func (storage *Storage) SetUserRole(id uint, role string) error {
+	if err := storage.DB.Model(&UserAccount{}).Where("id = ?", id).Update("role", role).Error; err != nil {
+		return errors.New("couldn't update user in postgres storage")
+	}
+	return nil
+}
+
+// SetUserDisabled disables the user with the given reason, or enables them again if disabled is false.
+func (storage *Storage) SetUserDisabled(id uint, disabled bool, reason string) error {
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	if err := storage.DB.Model(&UserAccount{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"disabled_at":     disabledAt,
+		"disabled_reason": reason,
+	}).Error; err != nil {
+		return errors.New("couldn't update user in postgres storage")
+	}
+	return nil
+}
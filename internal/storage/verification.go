@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// VerificationCode is a code sent to the email address or phone number (Target) of a user to verify it. Only the
+// hash of the code is stored.
+type VerificationCode struct {
+	gorm.Model
+	UserID    uint        `gorm:"index"`
+	User      UserAccount `gorm:"ONDELETE:CASCADE"`
+	Channel   string
+	Target    string
+	CodeHash  string
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// CreateVerificationCode stores a new code and invalidates the previous codes of the user on the same channel.
+func (storage *Storage) CreateVerificationCode(code *VerificationCode) error {
+	if err := storage.DB.Model(&VerificationCode{}).
+		Where("user_id = ? AND channel = ? AND used_at IS NULL", code.UserID, code.Channel).
+		Update("used_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't invalidate verification codes in postgres storage")
+	}
+	if err := storage.DB.Create(code).Error; err != nil {
+		return errors.New("couldn't create verification code in postgres storage")
+	}
+	return nil
+}
+
+// GetLastVerificationCode returns the most recent code sent to the user on a channel, used or not.
+func (storage *Storage) GetLastVerificationCode(userID uint, channel string) (*VerificationCode, error) {
+	code := VerificationCode{}
+	storage.DB.Where("user_id = ? AND channel = ?", userID, channel).Order("id desc").First(&code)
+	if code.ID == 0 {
+		return nil, errors.New("verification code not found")
+	}
+	return &code, nil
+}
+
+// AddVerificationAttempt counts an attempt to enter a code. It reports false if the code is used, expired or out of
+// attempts.
+func (storage *Storage) AddVerificationAttempt(id uint, maxAttempts int) (bool, error) {
+	result := storage.DB.Model(&VerificationCode{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ? AND attempts < ?", id, time.Now(), maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return false, errors.New("couldn't update verification code in postgres storage")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (storage *Storage) UseVerificationCode(id uint) error {
+	if err := storage.DB.Model(&VerificationCode{}).Where("id = ?", id).
+		Update("used_at", time.Now()).Error; err != nil {
+		return errors.New("couldn't update verification code in postgres storage")
+	}
+	return nil
+}
@@ -2,59 +2,81 @@ package token
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
-	"strconv"
 	"time"
 )
 
+// supportedMethods are the signing methods of KeySet, by algorithm.
+var supportedMethods = map[string]jwt.SigningMethod{
+	AlgRS256: jwt.SigningMethodRS256,
+	AlgEdDSA: jwt.SigningMethodEdDSA,
+}
+
 type JWTManager struct {
-	secret  []byte
-	Storage *storage.Storage
-	RDB     *redis.Client
+	keys *KeySet
+	RDB  *redis.Client
 }
 
-func NewJWTManager(secret string, store *storage.Storage, rdb *redis.Client) *JWTManager {
+func NewJWTManager(keys *KeySet, rdb *redis.Client) *JWTManager {
 	return &JWTManager{
-		secret:  []byte(secret),
-		Storage: store,
-		RDB:     rdb,
+		keys: keys,
+		RDB:  rdb,
 	}
 }
 
+// Generate signs the given claims. Tokens are issued now and get a random id unless claims has one.
 func (m *JWTManager) Generate(claims map[string]string, expiration time.Time) (string, error) {
 	mapClaims := jwt.MapClaims{
-		"exp": expiration.Unix(),
+		"exp":   expiration.Unix(),
+		"iat":   time.Now().Unix(),
+		ClaimID: uuid.New().String(),
 	}
 	for key, value := range claims {
 		mapClaims[key] = value
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
+	key, err := m.keys.signingKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(supportedMethods[key.alg], mapClaims)
+	token.Header["kid"] = key.kid
 
-	tokenString, err := token.SignedString(m.secret)
+	tokenString, err := token.SignedString(key.private)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func (m *JWTManager) Validate(ctx context.Context, tokenString string) (map[string]string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return m.secret, nil
+// parse verifies the signature of a token with the key named by its kid header. The algorithm is pinned to the one
+// of the key, so a token can't pick how it is verified.
+func (m *JWTManager) parse(tokenString string) (*jwt.Token, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}))
+	return parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := m.keys.verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("token is signed with %s instead of %s", token.Method.Alg(), key.alg)
+		}
+		return key.private.Public(), nil
 	})
+}
+
+func (m *JWTManager) Validate(ctx context.Context, tokenString string) (map[string]string, error) {
+	token, err := m.parse(tokenString)
 	if err != nil {
 		return nil, err
 	}
 	if !token.Valid {
 		return nil, errors.New("invalid token")
 	}
-	err = m.CheckUnauthorizedToken(ctx, tokenString)
-	if err != nil {
-		return nil, errors.New("invalid token")
-	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
@@ -69,59 +91,26 @@ func (m *JWTManager) Validate(ctx context.Context, tokenString string) (map[stri
 	return result, nil
 }
 
-func (m *JWTManager) InvalidateToken(ctx context.Context, tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return m.secret, nil
-	})
+func (m *JWTManager) ValidateType(ctx context.Context, tokenString string, typ string) (map[string]string, error) {
+	claims, err := m.Validate(ctx, tokenString)
 	if err != nil {
-		return err
-	}
-	if !token.Valid {
-		return errors.New("invalid token")
-	}
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return errors.New("invalid token")
+		return nil, err
 	}
-	userId, _ := strconv.Atoi(claims["userID"].(string))
-	var expiration time.Time
-	switch exp := claims["exp"].(type) {
-	case float64:
-		expiration = time.Unix(int64(exp), 0)
-	case json.Number:
-		v, _ := exp.Int64()
-		expiration = time.Unix(v, 0)
+	if claims[ClaimType] != typ {
+		return nil, errors.New("invalid token type")
 	}
-	err = m.Storage.CreateUnauthorizedToken(&storage.UnauthorizedToken{
-		UserID:     uint(userId),
-		Token:      tokenString,
-		Expiration: expiration,
-	})
-	m.RDB.SetEx(ctx, tokenString, "false", time.Until(expiration))
-	if err != nil {
-		return err
-	}
-	return nil
+	return claims, nil
 }
 
-func (m *JWTManager) CheckUnauthorizedToken(ctx context.Context, tokenString string) error {
-	resp := m.RDB.Get(ctx, tokenString)
-	if resp.Err() == redis.Nil {
-		_, err := m.Storage.GetUnauthorizedToken(tokenString)
-		if err != nil {
-			m.RDB.SetEx(ctx, tokenString, "true", time.Minute*10)
-			return nil
-		}
-		m.RDB.SetEx(ctx, tokenString, "false", time.Minute*10)
-	} else if resp.Err() != nil {
-		_, err := m.Storage.GetUnauthorizedToken(tokenString)
-		if err != nil {
-			m.RDB.SetEx(ctx, tokenString, "true", time.Minute*10)
-			return nil
-		}
-		m.RDB.SetEx(ctx, tokenString, "false", time.Minute*10)
-	} else if resp.Val() == "true" {
-		return nil
+// UseOnce marks the token with the given id as used and reports whether it was unused, for tokens that can be
+// exchanged once, like two factor challenges. ttl has to cover the rest of the lifetime of the token.
+func (m *JWTManager) UseOnce(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	if id == "" {
+		return false, errors.New("token has no id")
 	}
-	return errors.New("token is unauthorized")
+	return m.RDB.SetNX(ctx, usedTokenKey(id), 1, ttl).Result()
+}
+
+func usedTokenKey(id string) string {
+	return "token:used:" + id
 }
@@ -0,0 +1,334 @@
+package token
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// Signing algorithms of KeySet.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// keyReloadInterval is how often a KeySet reloads the keys, to pick up keys added by other instances.
+const keyReloadInterval = time.Minute
+
+// rsaKeyBits is the size of generated RS256 keys.
+const rsaKeyBits = 2048
+
+// Config configures tokens and the rotation of their signing keys. A new key is added every RotationInterval
+// seconds and published PublishAhead seconds before it starts signing, so verifiers fetching the key set see it
+// first. A replaced key still verifies tokens until the last one it signed expires. Private keys are stored
+// encrypted with EncryptionKey, 32 base64 encoded bytes.
+type Config struct {
+	Algorithm          string
+	EncryptionKey      string `mapstructure:"encryption_key" yaml:"encryption_key"`
+	AuthTokenExpire    int64  `mapstructure:"auth_token_expire" yaml:"auth_token_expire"`
+	RefreshTokenExpire int64  `mapstructure:"refresh_token_expire" yaml:"refresh_token_expire"`
+	RotationInterval   int64  `mapstructure:"rotation_interval" yaml:"rotation_interval"`
+	PublishAhead       int64  `mapstructure:"publish_ahead" yaml:"publish_ahead"`
+}
+
+type signingKey struct {
+	id          uint
+	kid         string
+	alg         string
+	private     crypto.Signer
+	activatesAt time.Time
+}
+
+// KeySet holds the keys tokens are signed and verified with. The keys are stored in postgres so all instances
+// share them, encrypted with AES-GCM so reading the database is not enough to sign tokens.
+type KeySet struct {
+	storage          *storage.Storage
+	aead             cipher.AEAD
+	alg              string
+	rotationInterval time.Duration
+	publishAhead     time.Duration
+	maxTokenLifetime time.Duration
+
+	mu       sync.Mutex
+	keys     []signingKey
+	loadedAt time.Time
+}
+
+func NewKeySet(store *storage.Storage, config Config) (*KeySet, error) {
+	key, err := base64.StdEncoding.DecodeString(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	maxTokenLifetime := config.AuthTokenExpire
+	if config.RefreshTokenExpire > maxTokenLifetime {
+		maxTokenLifetime = config.RefreshTokenExpire
+	}
+	return &KeySet{
+		storage:          store,
+		aead:             aead,
+		alg:              config.Algorithm,
+		rotationInterval: time.Duration(config.RotationInterval) * time.Second,
+		publishAhead:     time.Duration(config.PublishAhead) * time.Second,
+		maxTokenLifetime: time.Duration(maxTokenLifetime) * time.Second,
+	}, nil
+}
+
+// load returns the keys, oldest first, reloading them if they are older than keyReloadInterval or force is set.
+func (s *KeySet) load(force bool) ([]signingKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !force && s.loadedAt.After(time.Now().Add(-keyReloadInterval)) {
+		return s.keys, nil
+	}
+	stored, err := s.storage.GetSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]signingKey, 0, len(stored))
+	for _, k := range stored {
+		private, err := s.decryptPrivateKey(k.PrivateKey, k.Kid)
+		if err != nil {
+			logrus.Errorf("failed to decode signing key %s: %v", k.Kid, err)
+			continue
+		}
+		keys = append(keys, signingKey{
+			id:          k.ID,
+			kid:         k.Kid,
+			alg:         k.Alg,
+			private:     private,
+			activatesAt: k.ActivatesAt,
+		})
+	}
+	s.keys = keys
+	s.loadedAt = time.Now()
+	return s.keys, nil
+}
+
+// signingKey returns the key new tokens are signed with.
+func (s *KeySet) signingKey() (*signingKey, error) {
+	keys, err := s.load(false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !keys[i].activatesAt.After(now) {
+			return &keys[i], nil
+		}
+	}
+	return nil, errors.New("no active signing key")
+}
+
+// verificationKey returns the key with the given id. Unknown ids reload the keys, in case another instance just
+// added the key.
+func (s *KeySet) verificationKey(kid string) (*signingKey, error) {
+	keys, err := s.load(false)
+	if err != nil {
+		return nil, err
+	}
+	if key := findKey(keys, kid); key != nil {
+		return key, nil
+	}
+	s.mu.Lock()
+	recent := s.loadedAt.After(time.Now().Add(-time.Second))
+	s.mu.Unlock()
+	if !recent {
+		keys, err = s.load(true)
+		if err != nil {
+			return nil, err
+		}
+		if key := findKey(keys, kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func findKey(keys []signingKey, kid string) *signingKey {
+	for i := range keys {
+		if keys[i].kid == kid {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// Rotate adds a new key if the newest one is due to be replaced within PublishAhead, or there is no key at all.
+func (s *KeySet) Rotate() error {
+	keys, err := s.load(true)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	activatesAt := now
+	var latestID uint
+	if len(keys) > 0 {
+		latest := keys[len(keys)-1]
+		due := latest.activatesAt.Add(s.rotationInterval)
+		if due.Sub(now) > s.publishAhead {
+			return nil
+		}
+		latestID = latest.id
+		activatesAt = now.Add(s.publishAhead)
+		if due.After(activatesAt) {
+			activatesAt = due
+		}
+	}
+	privateKey, err := generatePrivateKey(s.alg)
+	if err != nil {
+		return err
+	}
+	kid := uuid.New().String()
+	encrypted, err := s.encryptPrivateKey(privateKey, kid)
+	if err != nil {
+		return err
+	}
+	key := &storage.SigningKey{
+		Kid:         kid,
+		Alg:         s.alg,
+		PrivateKey:  encrypted,
+		ActivatesAt: activatesAt,
+	}
+	added, err := s.storage.AddSigningKey(key, latestID, activatesAt.Add(s.maxTokenLifetime))
+	if err != nil {
+		return err
+	}
+	if added {
+		logrus.WithField("kid", key.Kid).Infof("added signing key activating at %s", activatesAt)
+	}
+	_, err = s.load(true)
+	return err
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys as a JSON Web Key Set, including keys that do not sign yet.
+func (s *KeySet) JWKS() ([]byte, error) {
+	keys, err := s.load(false)
+	if err != nil {
+		return nil, err
+	}
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{Keys: make([]jsonWebKey, 0, len(keys))}
+	for _, k := range keys {
+		jwk := jsonWebKey{Kid: k.kid, Use: "sig", Alg: k.alg}
+		switch public := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return json.Marshal(jwks)
+}
+
+// generatePrivateKey returns a new PKCS #8 PEM encoded key for the algorithm.
+func generatePrivateKey(alg string) (string, error) {
+	var key interface{}
+	var err error
+	switch alg {
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+	return signer, nil
+}
+
+// encryptPrivateKey seals a PEM encoded key, bound to its kid so it can't be moved to another key.
+func (s *KeySet) encryptPrivateKey(encoded string, kid string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(encoded), []byte("kid:"+kid))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptPrivateKey opens a key sealed by encryptPrivateKey. Keys stored before they were encrypted are PEM encoded
+// as is; they are still used until they expire.
+func (s *KeySet) decryptPrivateKey(encrypted string, kid string) (crypto.Signer, error) {
+	if strings.HasPrefix(encrypted, "-----BEGIN") {
+		return decodePrivateKey(encrypted)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, errors.New("encrypted key is too short")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	encoded, err := s.aead.Open(nil, nonce, ciphertext, []byte("kid:"+kid))
+	if err != nil {
+		return nil, err
+	}
+	return decodePrivateKey(string(encoded))
+}
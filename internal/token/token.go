@@ -5,8 +5,33 @@ import (
 	"time"
 )
 
+// Claims set on every token.
+const (
+	ClaimUserID = "userID"
+	// ClaimType is the type of the token, TypeAccess, TypeRefresh or TypeTwoFactor.
+	ClaimType = "typ"
+	// ClaimID uniquely identifies a token.
+	ClaimID = "jti"
+	// ClaimSession is the id of the session the token was issued for.
+	ClaimSession = "sid"
+	// ClaimDevice is the device name a two factor challenge starts the session with.
+	ClaimDevice = "dev"
+)
+
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+	// TypeTwoFactor is a challenge issued after the password of a user with two factor authentication, to be
+	// exchanged for access and refresh tokens with a code.
+	TypeTwoFactor = "2fa"
+)
+
 type Manager interface {
 	Generate(claims map[string]string, expiration time.Time) (string, error)
 	Validate(ctx context.Context, tokenString string) (map[string]string, error)
-	InvalidateToken(ctx context.Context, tokenString string) error
+	// ValidateType validates a token and makes sure it is of the given type.
+	ValidateType(ctx context.Context, tokenString string, typ string) (map[string]string, error)
+	// UseOnce marks a token as used by its id and reports whether it was unused. Revoking sessions is up to the
+	// session rows, this is for tokens that can be exchanged once.
+	UseOnce(ctx context.Context, id string, ttl time.Duration) (bool, error)
 }
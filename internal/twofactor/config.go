@@ -0,0 +1,11 @@
+package twofactor
+
+// Config configures two factor authentication. Secrets are encrypted with EncryptionKey, 32 base64 encoded bytes.
+// Issuer names the service in authenticator apps. After the password, a login has ChallengeExpire seconds to enter
+// a code. Users get RecoveryCodes codes to log in without their authenticator.
+type Config struct {
+	Issuer          string
+	EncryptionKey   string `mapstructure:"encryption_key" yaml:"encryption_key"`
+	ChallengeExpire int64  `mapstructure:"challenge_expire" yaml:"challenge_expire"`
+	RecoveryCodes   int    `mapstructure:"recovery_codes" yaml:"recovery_codes"`
+}
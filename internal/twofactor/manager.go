@@ -0,0 +1,223 @@
+package twofactor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+var (
+	ErrAlreadyEnabled = errors.New("two factor authentication is already enabled")
+	ErrNotEnrolled    = errors.New("two factor authentication is not enrolled")
+	ErrNotEnabled     = errors.New("two factor authentication is not enabled")
+	ErrWrongCode      = errors.New("wrong two factor code")
+)
+
+// secretSize is the size of generated secrets, 160 bits as RFC 4226 recommends for HMAC-SHA1.
+const secretSize = 20
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// twoFactorStore is the part of the storage the manager keeps authenticators and recovery codes in.
+type twoFactorStore interface {
+	GetTwoFactor(userID uint) (*storage.TwoFactor, error)
+	CreateTwoFactor(twoFactor *storage.TwoFactor) error
+	ConfirmTwoFactor(userID uint, step int64, codeHashes []string) error
+	UseTwoFactorStep(userID uint, step int64) (bool, error)
+	UseRecoveryCode(userID uint, codeHash string) (bool, error)
+	DeleteTwoFactor(userID uint) error
+}
+
+// Manager enrolls users in two factor authentication and checks their codes. Secrets are stored encrypted with
+// AES-GCM, recovery codes hashed.
+type Manager struct {
+	storage twoFactorStore
+	config  Config
+	aead    cipher.AEAD
+	now     func() time.Time
+}
+
+func NewManager(store *storage.Storage, config Config) (*Manager, error) {
+	key, err := base64.StdEncoding.DecodeString(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		storage: store,
+		config:  config,
+		aead:    aead,
+		now:     time.Now,
+	}, nil
+}
+
+// ChallengeExpiration returns when a login challenge issued now expires.
+func (m *Manager) ChallengeExpiration() time.Time {
+	return time.Now().Add(time.Duration(m.config.ChallengeExpire) * time.Second)
+}
+
+// Enabled reports whether the user has confirmed an authenticator, so their logins need a code.
+func (m *Manager) Enabled(userID uint) bool {
+	twoFactor, err := m.storage.GetTwoFactor(userID)
+	return err == nil && twoFactor.ConfirmedAt != nil
+}
+
+// Enroll generates a new secret for the user and returns it along with its otpauth uri. It is used once the user
+// enters a code of it, see Confirm.
+func (m *Manager) Enroll(user *storage.UserAccount) (string, string, error) {
+	if m.Enabled(user.ID) {
+		return "", "", ErrAlreadyEnabled
+	}
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	encrypted, err := m.encrypt(secret, user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	err = m.storage.CreateTwoFactor(&storage.TwoFactor{
+		UserID:          user.ID,
+		SecretEncrypted: encrypted,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	account := user.Email
+	if account == "" {
+		account = user.PhoneNumber
+	}
+	return secretEncoding.EncodeToString(secret), keyURI(m.config.Issuer, account, secret), nil
+}
+
+// Confirm enables two factor authentication once the user enters a code of the enrolled secret, and returns their
+// recovery codes.
+func (m *Manager) Confirm(userID uint, code string) ([]string, error) {
+	twoFactor, err := m.storage.GetTwoFactor(userID)
+	if err != nil {
+		return nil, ErrNotEnrolled
+	}
+	if twoFactor.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+	secret, err := m.decrypt(twoFactor.SecretEncrypted, userID)
+	if err != nil {
+		return nil, err
+	}
+	step := matchStep(secret, code, m.now())
+	if step == 0 {
+		return nil, ErrWrongCode
+	}
+	codes := make([]string, 0, m.config.RecoveryCodes)
+	hashes := make([]string, 0, m.config.RecoveryCodes)
+	for i := 0; i < m.config.RecoveryCodes; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	if err := m.storage.ConfirmTwoFactor(userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a code of the authenticator of the user, or one of their recovery codes. Either can be used once.
+func (m *Manager) Verify(userID uint, code string) error {
+	twoFactor, err := m.storage.GetTwoFactor(userID)
+	if err != nil || twoFactor.ConfirmedAt == nil {
+		return ErrNotEnabled
+	}
+	secret, err := m.decrypt(twoFactor.SecretEncrypted, userID)
+	if err != nil {
+		return err
+	}
+	if step := matchStep(secret, code, m.now()); step != 0 {
+		ok, err := m.storage.UseTwoFactorStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrWrongCode
+		}
+		return nil
+	}
+	ok, err := m.storage.UseRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWrongCode
+	}
+	return nil
+}
+
+// Disable turns two factor authentication off, given a code like Verify.
+func (m *Manager) Disable(userID uint, code string) error {
+	if err := m.Verify(userID, code); err != nil {
+		return err
+	}
+	return m.storage.DeleteTwoFactor(userID)
+}
+
+// encrypt seals a secret, bound to its user so it can't be moved to another one.
+func (m *Manager) encrypt(secret []byte, userID uint) (string, error) {
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := m.aead.Seal(nonce, nonce, secret, additionalData(userID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *Manager) decrypt(encrypted string, userID uint) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < m.aead.NonceSize() {
+		return nil, errors.New("encrypted secret is too short")
+	}
+	nonce, ciphertext := sealed[:m.aead.NonceSize()], sealed[m.aead.NonceSize():]
+	return m.aead.Open(nil, nonce, ciphertext, additionalData(userID))
+}
+
+func additionalData(userID uint) []byte {
+	return []byte("user:" + strconv.Itoa(int(userID)))
+}
+
+// generateRecoveryCode returns a code like "abcde-fghij", 50 random bits.
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 7)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := recoveryEncoding.EncodeToString(raw)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode hashes a code as entered, ignoring case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
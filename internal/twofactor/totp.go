@@ -0,0 +1,65 @@
+package twofactor
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Codes are the RFC 6238 defaults, which every authenticator app supports: 6 digits of HMAC-SHA1 over 30 second
+// steps.
+const (
+	digits = 6
+	period = 30
+	// skew is the number of steps a code may be early or late, for clocks that are off.
+	skew = 1
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func step(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+func code(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
+
+// matchStep returns the step around now the code is for, or zero if it is for none.
+func matchStep(secret []byte, given string, now time.Time) int64 {
+	current := step(now)
+	for s := current - skew; s <= current+skew; s++ {
+		if subtle.ConstantTimeCompare([]byte(code(secret, s)), []byte(given)) == 1 {
+			return s
+		}
+	}
+	return 0
+}
+
+// keyURI returns the otpauth uri authenticator apps scan as a QR code.
+func keyURI(issuer string, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", secretEncoding.EncodeToString(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(period))
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}).String()
+}
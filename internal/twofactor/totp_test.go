@@ -0,0 +1,175 @@
+package twofactor
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+// rfcSecret is the SHA1 secret of the test vectors of RFC 6238, appendix B.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCodeRFC6238(t *testing.T) {
+	// the RFC lists 8 digit codes, of which ours are the last 6
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		got := code(rfcSecret, step(time.Unix(tc.unix, 0)))
+		if want := tc.want[len(tc.want)-digits:]; got != want {
+			t.Errorf("code at %d = %s, want %s", tc.unix, got, want)
+		}
+	}
+}
+
+func TestMatchStepSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := step(now)
+	for offset := int64(-skew - 1); offset <= skew+1; offset++ {
+		got := matchStep(rfcSecret, code(rfcSecret, current+offset), now)
+		want := current + offset
+		if offset < -skew || offset > skew {
+			want = 0
+		}
+		if got != want {
+			t.Errorf("matchStep of a code %d steps off = %d, want %d", offset, got, want)
+		}
+	}
+	if got := matchStep(rfcSecret, "000000", now); got != 0 {
+		t.Errorf("matchStep of a wrong code = %d, want 0", got)
+	}
+}
+
+func TestManagerVerify(t *testing.T) {
+	m, secret := enrolledManager(t)
+	now := m.now()
+	current := step(now)
+
+	if err := m.Verify(1, code(secret, current+skew+1)); err != ErrWrongCode {
+		t.Errorf("Verify of a code past the skew = %v, want ErrWrongCode", err)
+	}
+	if err := m.Verify(1, code(secret, current+skew)); err != nil {
+		t.Errorf("Verify of a code within the skew = %v, want nil", err)
+	}
+	// the code of the next step was used by now, so the codes of it and all steps before can't be used again
+	for s := current - skew; s <= current+skew; s++ {
+		if err := m.Verify(1, code(secret, s)); err != ErrWrongCode {
+			t.Errorf("Verify of a code of step %d after step %d was used = %v, want ErrWrongCode", s, current+skew, err)
+		}
+	}
+
+	// a minute later the next code works once
+	m.now = func() time.Time { return now.Add(2 * period * time.Second) }
+	next := code(secret, current+2)
+	if err := m.Verify(1, next); err != nil {
+		t.Errorf("Verify of a new code = %v, want nil", err)
+	}
+	if err := m.Verify(1, next); err != ErrWrongCode {
+		t.Errorf("Verify of a replayed code = %v, want ErrWrongCode", err)
+	}
+}
+
+func TestManagerVerifyRecoveryCode(t *testing.T) {
+	m, _ := enrolledManager(t)
+	recoveryCode := m.storage.(*memoryStore).recoveryCodes[0]
+	if err := m.Verify(1, recoveryCode); err != nil {
+		t.Errorf("Verify of a recovery code = %v, want nil", err)
+	}
+	if err := m.Verify(1, recoveryCode); err != ErrWrongCode {
+		t.Errorf("Verify of a used recovery code = %v, want ErrWrongCode", err)
+	}
+}
+
+// enrolledManager returns a manager whose user 1 confirmed two factor authentication with a code of the step
+// before now, along with the secret of the user.
+func enrolledManager(t *testing.T) (*Manager, []byte) {
+	t.Helper()
+	m, err := NewManager(nil, Config{
+		EncryptionKey: base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		RecoveryCodes: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memoryStore{}
+	m.storage = store
+	now := time.Unix(1111111111, 0)
+	m.now = func() time.Time { return now }
+
+	// the fixed secret rather than one of Enroll, so no two codes of the tests are the same by chance
+	encrypted, err := m.encrypt(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.twoFactor = &storage.TwoFactor{UserID: 1, SecretEncrypted: encrypted}
+	store.recoveryCodes, err = m.Confirm(1, code(rfcSecret, step(now)-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, rfcSecret
+}
+
+// memoryStore keeps the authenticator and recovery codes of user 1 in memory. It also keeps the recovery codes as
+// returned by Confirm, for the tests to use.
+type memoryStore struct {
+	twoFactor     *storage.TwoFactor
+	codeHashes    map[string]bool
+	recoveryCodes []string
+}
+
+func (s *memoryStore) GetTwoFactor(userID uint) (*storage.TwoFactor, error) {
+	if s.twoFactor == nil || userID != s.twoFactor.UserID {
+		return nil, errors.New("two factor not found")
+	}
+	twoFactor := *s.twoFactor
+	return &twoFactor, nil
+}
+
+func (s *memoryStore) CreateTwoFactor(twoFactor *storage.TwoFactor) error {
+	s.twoFactor = twoFactor
+	return nil
+}
+
+func (s *memoryStore) ConfirmTwoFactor(userID uint, step int64, codeHashes []string) error {
+	now := time.Now()
+	s.twoFactor.ConfirmedAt = &now
+	s.twoFactor.LastUsedStep = step
+	s.codeHashes = make(map[string]bool)
+	for _, hash := range codeHashes {
+		s.codeHashes[hash] = false
+	}
+	return nil
+}
+
+func (s *memoryStore) UseTwoFactorStep(userID uint, step int64) (bool, error) {
+	if s.twoFactor.LastUsedStep >= step {
+		return false, nil
+	}
+	s.twoFactor.LastUsedStep = step
+	return true, nil
+}
+
+func (s *memoryStore) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	used, ok := s.codeHashes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	s.codeHashes[codeHash] = true
+	return true, nil
+}
+
+func (s *memoryStore) DeleteTwoFactor(userID uint) error {
+	s.twoFactor = nil
+	s.codeHashes = nil
+	return nil
+}
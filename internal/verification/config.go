@@ -0,0 +1,31 @@
+package verification
+
+import "github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+
+// Features that can be restricted to verified users.
+const (
+	FeatureFavorites = "favorites"
+	FeatureHistory   = "history"
+)
+
+// Config configures verification codes. Codes expire after CodeExpire seconds and can be tried MaxAttempts times;
+// a new code can be requested every ResendInterval seconds. If EmailUrl is set, emails also link to it with the
+// {code} placeholder replaced. RequiredFor lists the features only users with a verified email address or phone
+// number can use.
+type Config struct {
+	CodeExpire     int64    `mapstructure:"code_expire" yaml:"code_expire"`
+	MaxAttempts    int      `mapstructure:"max_attempts" yaml:"max_attempts"`
+	ResendInterval int64    `mapstructure:"resend_interval" yaml:"resend_interval"`
+	EmailUrl       string   `mapstructure:"email_url" yaml:"email_url"`
+	RequiredFor    []string `mapstructure:"required_for" yaml:"required_for"`
+}
+
+// Allows reports whether the user may use a feature.
+func (c Config) Allows(user *storage.UserAccount, feature string) bool {
+	for _, f := range c.RequiredFor {
+		if f == feature {
+			return user.IsVerified()
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/mail"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/sms"
+	"github.com/web-programming-fall-2022/digivision-backend/internal/storage"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelPhone = "phone"
+)
+
+var (
+	ErrAlreadyVerified  = errors.New("already verified")
+	ErrTooSoon          = errors.New("a code was sent recently")
+	ErrNoCode           = errors.New("no active verification code")
+	ErrTooManyAttempts  = errors.New("too many attempts")
+	ErrWrongCode        = errors.New("wrong verification code")
+	ErrUnknownChannel   = errors.New("unknown verification channel")
+	ErrMissingRecipient = errors.New("nothing to verify on this channel")
+)
+
+// Verifier sends verification codes to the email addresses and phone numbers of users and checks them.
+type Verifier struct {
+	storage *storage.Storage
+	mailer  mail.Mailer
+	sms     sms.Sender
+	config  Config
+}
+
+func NewVerifier(store *storage.Storage, mailer mail.Mailer, smsSender sms.Sender, config Config) *Verifier {
+	return &Verifier{
+		storage: store,
+		mailer:  mailer,
+		sms:     smsSender,
+		config:  config,
+	}
+}
+
+// Send sends a new code to the email address or phone number of the user, invalidating the previous one.
+func (v *Verifier) Send(ctx context.Context, user *storage.UserAccount, channel string) error {
+	target, verified, err := recipient(user, channel)
+	if err != nil {
+		return err
+	}
+	if verified {
+		return ErrAlreadyVerified
+	}
+	last, err := v.storage.GetLastVerificationCode(user.ID, channel)
+	if err == nil && time.Since(last.CreatedAt) < time.Duration(v.config.ResendInterval)*time.Second {
+		return ErrTooSoon
+	}
+	code, err := generateCode()
+	if err != nil {
+		return err
+	}
+	err = v.storage.CreateVerificationCode(&storage.VerificationCode{
+		UserID:    user.ID,
+		Channel:   channel,
+		Target:    target,
+		CodeHash:  hashCode(target, code),
+		ExpiresAt: time.Now().Add(time.Duration(v.config.CodeExpire) * time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	minutes := v.config.CodeExpire / 60
+	if channel == ChannelPhone {
+		return v.sms.Send(ctx, target, fmt.Sprintf("Digivision code: %s\nValid for %d minutes.", code, minutes))
+	}
+	body := fmt.Sprintf("Your Digivision verification code is %s. It is valid for %d minutes.", code, minutes)
+	if v.config.EmailUrl != "" {
+		link := strings.ReplaceAll(v.config.EmailUrl, "{code}", url.QueryEscape(code))
+		body += fmt.Sprintf("\nYou can also verify your email by opening\n%s", link)
+	}
+	return v.mailer.Send(ctx, target, "Verify your Digivision email", body)
+}
+
+// Verify checks a code sent to the user and marks the email address or phone number as verified if it matches.
+func (v *Verifier) Verify(ctx context.Context, user *storage.UserAccount, channel string, code string) error {
+	target, verified, err := recipient(user, channel)
+	if err != nil {
+		return err
+	}
+	if verified {
+		return ErrAlreadyVerified
+	}
+	last, err := v.storage.GetLastVerificationCode(user.ID, channel)
+	if err != nil || last.UsedAt != nil || last.ExpiresAt.Before(time.Now()) || last.Target != target {
+		return ErrNoCode
+	}
+	ok, err := v.storage.AddVerificationAttempt(last.ID, v.config.MaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTooManyAttempts
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(target, code)), []byte(last.CodeHash)) != 1 {
+		return ErrWrongCode
+	}
+	if err := v.storage.UseVerificationCode(last.ID); err != nil {
+		return err
+	}
+	if channel == ChannelPhone {
+		return v.storage.SetPhoneVerified(user.ID)
+	}
+	return v.storage.SetEmailVerified(user.ID)
+}
+
+// recipient returns the email address or phone number verified on a channel and whether it is already verified.
+func recipient(user *storage.UserAccount, channel string) (string, bool, error) {
+	var target string
+	var verified bool
+	switch channel {
+	case ChannelEmail:
+		target, verified = user.Email, user.EmailVerifiedAt != nil
+	case ChannelPhone:
+		target, verified = user.PhoneNumber, user.PhoneVerifiedAt != nil
+	default:
+		return "", false, ErrUnknownChannel
+	}
+	if target == "" {
+		return "", false, ErrMissingRecipient
+	}
+	return target, verified, nil
+}
+
+// generateCode returns a random 6 digit code.
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashCode(target string, code string) string {
+	sum := sha256.Sum256([]byte(target + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,162 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: admin.proto
+
+package v1
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	_ "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
+	_ "github.com/mwitkow/go-proto-validators"
+	github_com_mwitkow_go_proto_validators "github.com/mwitkow/go-proto-validators"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+func (this *AdminUser) Validate() error {
+	return nil
+}
+func (this *ListUsersRequest) Validate() error {
+	if !(this.Limit < 101) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Limit", fmt.Errorf(`value '%v' must be less than '101'`, this.Limit))
+	}
+	return nil
+}
+func (this *ListUsersResponse) Validate() error {
+	for _, item := range this.Users {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Users", err)
+			}
+		}
+	}
+	return nil
+}
+func (this *GetUserRequest) Validate() error {
+	if !(this.UserId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("UserId", fmt.Errorf(`value '%v' must be greater than '0'`, this.UserId))
+	}
+	return nil
+}
+func (this *SetUserRoleRequest) Validate() error {
+	if !(this.UserId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("UserId", fmt.Errorf(`value '%v' must be greater than '0'`, this.UserId))
+	}
+	if this.Role == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Role", fmt.Errorf(`value '%v' must not be an empty string`, this.Role))
+	}
+	return nil
+}
+func (this *DisableUserRequest) Validate() error {
+	if !(this.UserId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("UserId", fmt.Errorf(`value '%v' must be greater than '0'`, this.UserId))
+	}
+	return nil
+}
+func (this *EnableUserRequest) Validate() error {
+	if !(this.UserId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("UserId", fmt.Errorf(`value '%v' must be greater than '0'`, this.UserId))
+	}
+	return nil
+}
+func (this *RevokeUserSessionsRequest) Validate() error {
+	if !(this.UserId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("UserId", fmt.Errorf(`value '%v' must be greater than '0'`, this.UserId))
+	}
+	return nil
+}
+func (this *GetUsageRequest) Validate() error {
+	if !(this.Days > -1) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Days", fmt.Errorf(`value '%v' must be greater than '-1'`, this.Days))
+	}
+	if !(this.Days < 366) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Days", fmt.Errorf(`value '%v' must be less than '366'`, this.Days))
+	}
+	return nil
+}
+func (this *DailyUsage) Validate() error {
+	return nil
+}
+func (this *GetUsageResponse) Validate() error {
+	for _, item := range this.Days {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Days", err)
+			}
+		}
+	}
+	return nil
+}
+func (this *ApiKey) Validate() error {
+	return nil
+}
+func (this *CreateApiKeyRequest) Validate() error {
+	if this.Name == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Name", fmt.Errorf(`value '%v' must not be an empty string`, this.Name))
+	}
+	if len(this.Scopes) < 1 {
+		return github_com_mwitkow_go_proto_validators.FieldError("Scopes", fmt.Errorf(`value '%v' must contain at least 1 elements`, this.Scopes))
+	}
+	if !(this.RateLimit > -1) {
+		return github_com_mwitkow_go_proto_validators.FieldError("RateLimit", fmt.Errorf(`value '%v' must be greater than '-1'`, this.RateLimit))
+	}
+	return nil
+}
+func (this *CreateApiKeyResponse) Validate() error {
+	if this.ApiKey != nil {
+		if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(this.ApiKey); err != nil {
+			return github_com_mwitkow_go_proto_validators.FieldError("ApiKey", err)
+		}
+	}
+	return nil
+}
+func (this *ListApiKeysRequest) Validate() error {
+	return nil
+}
+func (this *ListApiKeysResponse) Validate() error {
+	for _, item := range this.ApiKeys {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("ApiKeys", err)
+			}
+		}
+	}
+	return nil
+}
+func (this *RevokeApiKeyRequest) Validate() error {
+	if !(this.ApiKeyId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("ApiKeyId", fmt.Errorf(`value '%v' must be greater than '0'`, this.ApiKeyId))
+	}
+	return nil
+}
+func (this *GetApiKeyUsageRequest) Validate() error {
+	if !(this.ApiKeyId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("ApiKeyId", fmt.Errorf(`value '%v' must be greater than '0'`, this.ApiKeyId))
+	}
+	if !(this.Days > -1) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Days", fmt.Errorf(`value '%v' must be greater than '-1'`, this.Days))
+	}
+	if !(this.Days < 366) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Days", fmt.Errorf(`value '%v' must be less than '366'`, this.Days))
+	}
+	return nil
+}
+func (this *ApiKeyUsage) Validate() error {
+	return nil
+}
+func (this *GetApiKeyUsageResponse) Validate() error {
+	for _, item := range this.Usage {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Usage", err)
+			}
+		}
+	}
+	return nil
+}
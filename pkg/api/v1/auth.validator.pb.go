@@ -10,6 +10,7 @@ import (
 	_ "github.com/mwitkow/go-proto-validators"
 	github_com_mwitkow_go_proto_validators "github.com/mwitkow/go-proto-validators"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
+	_ "google.golang.org/genproto/googleapis/api/httpbody"
 	_ "google.golang.org/protobuf/types/known/emptypb"
 	math "math"
 	regexp "regexp"
@@ -67,3 +68,170 @@ func (this *UserInfoRequest) Validate() error {
 func (this *UserInfoResponse) Validate() error {
 	return nil
 }
+func (this *Session) Validate() error {
+	return nil
+}
+func (this *ListSessionsRequest) Validate() error {
+	return nil
+}
+func (this *ListSessionsResponse) Validate() error {
+	for _, item := range this.Sessions {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Sessions", err)
+			}
+		}
+	}
+	return nil
+}
+func (this *RevokeSessionRequest) Validate() error {
+	if !(this.SessionId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("SessionId", fmt.Errorf(`value '%v' must be greater than '0'`, this.SessionId))
+	}
+	return nil
+}
+func (this *RevokeAllOtherSessionsRequest) Validate() error {
+	return nil
+}
+func (this *ChangePasswordRequest) Validate() error {
+	if !(len(this.NewPassword) > 7) {
+		return github_com_mwitkow_go_proto_validators.FieldError("NewPassword", fmt.Errorf(`value '%v' must have a length greater than '7'`, this.NewPassword))
+	}
+	if !(len(this.NewPassword) < 65) {
+		return github_com_mwitkow_go_proto_validators.FieldError("NewPassword", fmt.Errorf(`value '%v' must have a length smaller than '65'`, this.NewPassword))
+	}
+	return nil
+}
+
+var _regex_RequestPasswordResetRequest_Email = regexp.MustCompile(`^[A-Za-z0-9+_.-]+@(.+)$`)
+
+func (this *RequestPasswordResetRequest) Validate() error {
+	if !_regex_RequestPasswordResetRequest_Email.MatchString(this.Email) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Email", fmt.Errorf(`value '%v' must be a string conforming to regex "^[A-Za-z0-9+_.-]+@(.+)$"`, this.Email))
+	}
+	return nil
+}
+func (this *ConfirmPasswordResetRequest) Validate() error {
+	if this.Token == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Token", fmt.Errorf(`value '%v' must not be an empty string`, this.Token))
+	}
+	if !(len(this.NewPassword) > 7) {
+		return github_com_mwitkow_go_proto_validators.FieldError("NewPassword", fmt.Errorf(`value '%v' must have a length greater than '7'`, this.NewPassword))
+	}
+	if !(len(this.NewPassword) < 65) {
+		return github_com_mwitkow_go_proto_validators.FieldError("NewPassword", fmt.Errorf(`value '%v' must have a length smaller than '65'`, this.NewPassword))
+	}
+	return nil
+}
+func (this *SendVerificationCodeRequest) Validate() error {
+	return nil
+}
+func (this *VerifyRequest) Validate() error {
+	if this.Code == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Code", fmt.Errorf(`value '%v' must not be an empty string`, this.Code))
+	}
+	return nil
+}
+
+var _regex_RequestOTPRequest_PhoneNumber = regexp.MustCompile(`^[0-9]{11}$`)
+
+func (this *RequestOTPRequest) Validate() error {
+	if !_regex_RequestOTPRequest_PhoneNumber.MatchString(this.PhoneNumber) {
+		return github_com_mwitkow_go_proto_validators.FieldError("PhoneNumber", fmt.Errorf(`value '%v' must be a string conforming to regex "^[0-9]{11}$"`, this.PhoneNumber))
+	}
+	return nil
+}
+
+var _regex_VerifyOTPRequest_PhoneNumber = regexp.MustCompile(`^[0-9]{11}$`)
+
+func (this *VerifyOTPRequest) Validate() error {
+	if !_regex_VerifyOTPRequest_PhoneNumber.MatchString(this.PhoneNumber) {
+		return github_com_mwitkow_go_proto_validators.FieldError("PhoneNumber", fmt.Errorf(`value '%v' must be a string conforming to regex "^[0-9]{11}$"`, this.PhoneNumber))
+	}
+	if this.Code == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Code", fmt.Errorf(`value '%v' must not be an empty string`, this.Code))
+	}
+	return nil
+}
+func (this *VerifyOTPResponse) Validate() error {
+	return nil
+}
+func (this *StartOIDCLoginRequest) Validate() error {
+	if this.Provider == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Provider", fmt.Errorf(`value '%v' must not be an empty string`, this.Provider))
+	}
+	return nil
+}
+func (this *StartOIDCLoginResponse) Validate() error {
+	return nil
+}
+func (this *FinishOIDCLoginRequest) Validate() error {
+	if this.Provider == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Provider", fmt.Errorf(`value '%v' must not be an empty string`, this.Provider))
+	}
+	if this.Code == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Code", fmt.Errorf(`value '%v' must not be an empty string`, this.Code))
+	}
+	if this.State == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("State", fmt.Errorf(`value '%v' must not be an empty string`, this.State))
+	}
+	return nil
+}
+func (this *FinishOIDCLoginResponse) Validate() error {
+	return nil
+}
+
+var _regex_UpdateProfileRequest_Gender = regexp.MustCompile(`^(M|F)?$`)
+var _regex_UpdateProfileRequest_PhoneNumber = regexp.MustCompile(`^([0-9]{11})?$`)
+
+func (this *UpdateProfileRequest) Validate() error {
+	if !_regex_UpdateProfileRequest_Gender.MatchString(this.Gender) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Gender", fmt.Errorf(`value '%v' must be a string conforming to regex "^(M|F)?$"`, this.Gender))
+	}
+	if !_regex_UpdateProfileRequest_PhoneNumber.MatchString(this.PhoneNumber) {
+		return github_com_mwitkow_go_proto_validators.FieldError("PhoneNumber", fmt.Errorf(`value '%v' must be a string conforming to regex "^([0-9]{11})?$"`, this.PhoneNumber))
+	}
+	return nil
+}
+func (this *ExportMyDataRequest) Validate() error {
+	return nil
+}
+func (this *DeleteAccountRequest) Validate() error {
+	return nil
+}
+func (this *CreateGuestRequest) Validate() error {
+	return nil
+}
+func (this *CreateGuestResponse) Validate() error {
+	return nil
+}
+func (this *EnrollTwoFactorRequest) Validate() error {
+	return nil
+}
+func (this *EnrollTwoFactorResponse) Validate() error {
+	return nil
+}
+func (this *ConfirmTwoFactorRequest) Validate() error {
+	if this.Code == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Code", fmt.Errorf(`value '%v' must not be an empty string`, this.Code))
+	}
+	return nil
+}
+func (this *ConfirmTwoFactorResponse) Validate() error {
+	return nil
+}
+func (this *DisableTwoFactorRequest) Validate() error {
+	if this.Code == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Code", fmt.Errorf(`value '%v' must not be an empty string`, this.Code))
+	}
+	return nil
+}
+func (this *VerifyTwoFactorRequest) Validate() error {
+	if this.ChallengeToken == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("ChallengeToken", fmt.Errorf(`value '%v' must not be an empty string`, this.ChallengeToken))
+	}
+	if this.Code == "" {
+		return github_com_mwitkow_go_proto_validators.FieldError("Code", fmt.Errorf(`value '%v' must not be an empty string`, this.Code))
+	}
+	return nil
+}
@@ -67,3 +67,24 @@ func (this *GetFavoritesResponse) Validate() error {
 	}
 	return nil
 }
+
+var _regex_UpdateFavoriteAlertRequest_ListName = regexp.MustCompile(`^favorites$`)
+
+func (this *UpdateFavoriteAlertRequest) Validate() error {
+	if !_regex_UpdateFavoriteAlertRequest_ListName.MatchString(this.ListName) {
+		return github_com_mwitkow_go_proto_validators.FieldError("ListName", fmt.Errorf(`value '%v' must be a string conforming to regex "^favorites$"`, this.ListName))
+	}
+	if !(this.ProductId > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("ProductId", fmt.Errorf(`value '%v' must be greater than '0'`, this.ProductId))
+	}
+	if !(this.PriceDropPercent > -1) {
+		return github_com_mwitkow_go_proto_validators.FieldError("PriceDropPercent", fmt.Errorf(`value '%v' must be greater than '-1'`, this.PriceDropPercent))
+	}
+	if !(this.PriceDropPercent < 100) {
+		return github_com_mwitkow_go_proto_validators.FieldError("PriceDropPercent", fmt.Errorf(`value '%v' must be less than '100'`, this.PriceDropPercent))
+	}
+	return nil
+}
+func (this *UpdateFavoriteAlertResponse) Validate() error {
+	return nil
+}
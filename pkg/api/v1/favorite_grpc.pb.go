@@ -25,6 +25,7 @@ type FavoriteServiceClient interface {
 	AddItemToFavorites(ctx context.Context, in *AddItemToFavoritesRequest, opts ...grpc.CallOption) (*AddItemToFavoritesResponse, error)
 	RemoveItemFromFavorites(ctx context.Context, in *RemoveItemFromFavoritesRequest, opts ...grpc.CallOption) (*RemoveItemFromFavoritesResponse, error)
 	GetFavorites(ctx context.Context, in *GetFavoritesRequest, opts ...grpc.CallOption) (*GetFavoritesResponse, error)
+	UpdateFavoriteAlert(ctx context.Context, in *UpdateFavoriteAlertRequest, opts ...grpc.CallOption) (*UpdateFavoriteAlertResponse, error)
 }
 
 type favoriteServiceClient struct {
@@ -62,6 +63,15 @@ func (c *favoriteServiceClient) GetFavorites(ctx context.Context, in *GetFavorit
 	return out, nil
 }
 
+func (c *favoriteServiceClient) UpdateFavoriteAlert(ctx context.Context, in *UpdateFavoriteAlertRequest, opts ...grpc.CallOption) (*UpdateFavoriteAlertResponse, error) {
+	out := new(UpdateFavoriteAlertResponse)
+	err := c.cc.Invoke(ctx, "/v1.FavoriteService/UpdateFavoriteAlert", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FavoriteServiceServer is the server API for FavoriteService service.
 // All implementations must embed UnimplementedFavoriteServiceServer
 // for forward compatibility
@@ -69,6 +79,7 @@ type FavoriteServiceServer interface {
 	AddItemToFavorites(context.Context, *AddItemToFavoritesRequest) (*AddItemToFavoritesResponse, error)
 	RemoveItemFromFavorites(context.Context, *RemoveItemFromFavoritesRequest) (*RemoveItemFromFavoritesResponse, error)
 	GetFavorites(context.Context, *GetFavoritesRequest) (*GetFavoritesResponse, error)
+	UpdateFavoriteAlert(context.Context, *UpdateFavoriteAlertRequest) (*UpdateFavoriteAlertResponse, error)
 	mustEmbedUnimplementedFavoriteServiceServer()
 }
 
@@ -85,6 +96,9 @@ func (UnimplementedFavoriteServiceServer) RemoveItemFromFavorites(context.Contex
 func (UnimplementedFavoriteServiceServer) GetFavorites(context.Context, *GetFavoritesRequest) (*GetFavoritesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetFavorites not implemented")
 }
+func (UnimplementedFavoriteServiceServer) UpdateFavoriteAlert(context.Context, *UpdateFavoriteAlertRequest) (*UpdateFavoriteAlertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateFavoriteAlert not implemented")
+}
 func (UnimplementedFavoriteServiceServer) mustEmbedUnimplementedFavoriteServiceServer() {}
 
 // UnsafeFavoriteServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -152,6 +166,24 @@ func _FavoriteService_GetFavorites_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FavoriteService_UpdateFavoriteAlert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateFavoriteAlertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FavoriteServiceServer).UpdateFavoriteAlert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1.FavoriteService/UpdateFavoriteAlert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FavoriteServiceServer).UpdateFavoriteAlert(ctx, req.(*UpdateFavoriteAlertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FavoriteService_ServiceDesc is the grpc.ServiceDesc for FavoriteService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -171,6 +203,10 @@ var FavoriteService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetFavorites",
 			Handler:    _FavoriteService_GetFavorites_Handler,
 		},
+		{
+			MethodName: "UpdateFavoriteAlert",
+			Handler:    _FavoriteService_UpdateFavoriteAlert_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "favorite.proto",
@@ -7,6 +7,7 @@ import (
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
 	_ "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
+	_ "github.com/mwitkow/go-proto-validators"
 	github_com_mwitkow_go_proto_validators "github.com/mwitkow/go-proto-validators"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	math "math"
@@ -49,6 +50,110 @@ func (this *Product) Validate() error {
 	}
 	return nil
 }
+func (this *ProductVariant) Validate() error {
+	return nil
+}
+func (this *ProductSpec) Validate() error {
+	return nil
+}
+func (this *ProductDetails) Validate() error {
+	if this.Product != nil {
+		if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(this.Product); err != nil {
+			return github_com_mwitkow_go_proto_validators.FieldError("Product", err)
+		}
+	}
+	for _, item := range this.Variants {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Variants", err)
+			}
+		}
+	}
+	for _, item := range this.Specs {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Specs", err)
+			}
+		}
+	}
+	return nil
+}
+func (this *GetProductRequest) Validate() error {
+	if !(this.Id > 0) {
+		return github_com_mwitkow_go_proto_validators.FieldError("Id", fmt.Errorf(`value '%v' must be greater than '0'`, this.Id))
+	}
+	return nil
+}
+func (this *GetProductResponse) Validate() error {
+	if this.Product != nil {
+		if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(this.Product); err != nil {
+			return github_com_mwitkow_go_proto_validators.FieldError("Product", err)
+		}
+	}
+	return nil
+}
+func (this *GetPriceHistoryRequest) Validate() error {
+	return nil
+}
+func (this *PricePoint) Validate() error {
+	return nil
+}
+func (this *PriceStats) Validate() error {
+	return nil
+}
+func (this *GetPriceHistoryResponse) Validate() error {
+	for _, item := range this.Points {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Points", err)
+			}
+		}
+	}
+	for _, item := range this.Stats {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Stats", err)
+			}
+		}
+	}
+	return nil
+}
+func (this *FetchFailure) Validate() error {
+	return nil
+}
+func (this *CategoryFacet) Validate() error {
+	return nil
+}
+func (this *PriceBucket) Validate() error {
+	return nil
+}
+func (this *RatingBand) Validate() error {
+	return nil
+}
+func (this *Facets) Validate() error {
+	for _, item := range this.Categories {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Categories", err)
+			}
+		}
+	}
+	for _, item := range this.Prices {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Prices", err)
+			}
+		}
+	}
+	for _, item := range this.Ratings {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Ratings", err)
+			}
+		}
+	}
+	return nil
+}
 func (this *SearchResponse) Validate() error {
 	for _, item := range this.Products {
 		if item != nil {
@@ -57,6 +162,18 @@ func (this *SearchResponse) Validate() error {
 			}
 		}
 	}
+	for _, item := range this.Failures {
+		if item != nil {
+			if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(item); err != nil {
+				return github_com_mwitkow_go_proto_validators.FieldError("Failures", err)
+			}
+		}
+	}
+	if this.Facets != nil {
+		if err := github_com_mwitkow_go_proto_validators.CallValidatorIfExists(this.Facets); err != nil {
+			return github_com_mwitkow_go_proto_validators.FieldError("Facets", err)
+		}
+	}
 	return nil
 }
 func (this *AsyncSearchResponse) Validate() error {
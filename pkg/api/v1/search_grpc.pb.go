@@ -25,6 +25,8 @@ type SearchServiceClient interface {
 	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
 	AsyncSearch(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (SearchService_AsyncSearchClient, error)
 	Crop(ctx context.Context, in *CropRequest, opts ...grpc.CallOption) (*CropResponse, error)
+	GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*GetProductResponse, error)
+	GetPriceHistory(ctx context.Context, in *GetPriceHistoryRequest, opts ...grpc.CallOption) (*GetPriceHistoryResponse, error)
 	GetSearchHistories(ctx context.Context, in *GetSearchHistoriesRequest, opts ...grpc.CallOption) (*GetSearchHistoriesResponse, error)
 }
 
@@ -86,6 +88,24 @@ func (c *searchServiceClient) Crop(ctx context.Context, in *CropRequest, opts ..
 	return out, nil
 }
 
+func (c *searchServiceClient) GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*GetProductResponse, error) {
+	out := new(GetProductResponse)
+	err := c.cc.Invoke(ctx, "/v1.SearchService/GetProduct", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) GetPriceHistory(ctx context.Context, in *GetPriceHistoryRequest, opts ...grpc.CallOption) (*GetPriceHistoryResponse, error) {
+	out := new(GetPriceHistoryResponse)
+	err := c.cc.Invoke(ctx, "/v1.SearchService/GetPriceHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *searchServiceClient) GetSearchHistories(ctx context.Context, in *GetSearchHistoriesRequest, opts ...grpc.CallOption) (*GetSearchHistoriesResponse, error) {
 	out := new(GetSearchHistoriesResponse)
 	err := c.cc.Invoke(ctx, "/v1.SearchService/GetSearchHistories", in, out, opts...)
@@ -102,6 +122,8 @@ type SearchServiceServer interface {
 	Search(context.Context, *SearchRequest) (*SearchResponse, error)
 	AsyncSearch(*SearchRequest, SearchService_AsyncSearchServer) error
 	Crop(context.Context, *CropRequest) (*CropResponse, error)
+	GetProduct(context.Context, *GetProductRequest) (*GetProductResponse, error)
+	GetPriceHistory(context.Context, *GetPriceHistoryRequest) (*GetPriceHistoryResponse, error)
 	GetSearchHistories(context.Context, *GetSearchHistoriesRequest) (*GetSearchHistoriesResponse, error)
 	mustEmbedUnimplementedSearchServiceServer()
 }
@@ -119,6 +141,12 @@ func (UnimplementedSearchServiceServer) AsyncSearch(*SearchRequest, SearchServic
 func (UnimplementedSearchServiceServer) Crop(context.Context, *CropRequest) (*CropResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Crop not implemented")
 }
+func (UnimplementedSearchServiceServer) GetProduct(context.Context, *GetProductRequest) (*GetProductResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProduct not implemented")
+}
+func (UnimplementedSearchServiceServer) GetPriceHistory(context.Context, *GetPriceHistoryRequest) (*GetPriceHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPriceHistory not implemented")
+}
 func (UnimplementedSearchServiceServer) GetSearchHistories(context.Context, *GetSearchHistoriesRequest) (*GetSearchHistoriesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSearchHistories not implemented")
 }
@@ -192,6 +220,42 @@ func _SearchService_Crop_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SearchService_GetProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProductRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).GetProduct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1.SearchService/GetProduct",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).GetProduct(ctx, req.(*GetProductRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_GetPriceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPriceHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).GetPriceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1.SearchService/GetPriceHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).GetPriceHistory(ctx, req.(*GetPriceHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _SearchService_GetSearchHistories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetSearchHistoriesRequest)
 	if err := dec(in); err != nil {
@@ -225,6 +289,14 @@ var SearchService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Crop",
 			Handler:    _SearchService_Crop_Handler,
 		},
+		{
+			MethodName: "GetProduct",
+			Handler:    _SearchService_GetProduct_Handler,
+		},
+		{
+			MethodName: "GetPriceHistory",
+			Handler:    _SearchService_GetPriceHistory_Handler,
+		},
 		{
 			MethodName: "GetSearchHistories",
 			Handler:    _SearchService_GetSearchHistories_Handler,